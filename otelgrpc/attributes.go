@@ -0,0 +1,127 @@
+// Copyright 2024 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgrpc
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// spanName returns the name of the span for the full method name "/package.Service/Method", which is "package.Service/Method".
+func spanName(fullMethod string) string {
+	return strings.TrimPrefix(fullMethod, "/")
+}
+
+// methodAttributes returns the rpc.system, rpc.service and rpc.method attributes of the full method name.
+func methodAttributes(fullMethod string) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{semconv.RPCSystemGRPC}
+	service, method, found := strings.Cut(spanName(fullMethod), "/")
+	if !found {
+		return attributes
+	}
+	if service != "" {
+		attributes = append(attributes, semconv.RPCService(service))
+	}
+	if method != "" {
+		attributes = append(attributes, semconv.RPCMethod(method))
+	}
+	return attributes
+}
+
+// peerAttributes returns the network.peer.address and network.peer.port attributes of the peer stored in ctx.
+func peerAttributes(ctx context.Context) []attribute.KeyValue {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+	host, port := splitHostPort(p.Addr.String())
+	if host == "" {
+		return nil
+	}
+	attributes := []attribute.KeyValue{semconv.NetworkPeerAddress(host)}
+	if port > 0 {
+		attributes = append(attributes, semconv.NetworkPeerPort(port))
+	}
+	return attributes
+}
+
+// targetAttributes returns the server.address and server.port attributes of a client connection target,
+// a target such as "dns:///localhost:50051" is reduced to its authority.
+func targetAttributes(target string) []attribute.KeyValue {
+	if i := strings.LastIndex(target, "/"); i >= 0 {
+		target = target[i+1:]
+	}
+	host, port := splitHostPort(target)
+	if host == "" {
+		return nil
+	}
+	attributes := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if port > 0 {
+		attributes = append(attributes, semconv.ServerPort(port))
+	}
+	return attributes
+}
+
+// splitHostPort splits a host with an optional port, the port is -1 when absent or invalid.
+func splitHostPort(hostport string) (string, int) {
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		// no port present.
+		return strings.Trim(hostport, "[]"), -1
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return host, -1
+	}
+	return host, port
+}
+
+// statusCodeAttribute returns the rpc.grpc.status_code attribute.
+func statusCodeAttribute(code codes.Code) attribute.KeyValue {
+	return semconv.RPCGRPCStatusCodeKey.Int(int(code))
+}
+
+// serverErrorCodes are the status codes which indicate an error of the server, other codes are caused by the client.
+var serverErrorCodes = map[codes.Code]struct{}{
+	codes.Unknown:          {},
+	codes.DeadlineExceeded: {},
+	codes.Unimplemented:    {},
+	codes.Internal:         {},
+	codes.Unavailable:      {},
+	codes.DataLoss:         {},
+}
+
+// spanStatus maps the gRPC status to the status of a span. A client span has an error status for every code other than OK,
+// a server span only for the codes in serverErrorCodes.
+func spanStatus(s *status.Status, server bool) (otelcodes.Code, string) {
+	if s.Code() == codes.OK {
+		return otelcodes.Unset, ""
+	}
+	if server {
+		if _, ok := serverErrorCodes[s.Code()]; !ok {
+			return otelcodes.Unset, ""
+		}
+	}
+	return otelcodes.Error, s.Message()
+}
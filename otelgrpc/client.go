@@ -0,0 +1,171 @@
+// Copyright 2024 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgrpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns a grpc.UnaryClientInterceptor which starts a client trace.Span for every call.
+// The span context is injected into the outgoing metadata and the duration is recorded as rpc.client.duration.
+func UnaryClientInterceptor(opt ...Option) grpc.UnaryClientInterceptor {
+	c := newConfig(opt)
+	metrics := newClientMetrics(c.meterProvider)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, span := c.startClientSpan(ctx, method, cc)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		c.finishClient(ctx, span, metrics, method, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a grpc.StreamClientInterceptor which starts a client trace.Span for every stream.
+// The span ends when the stream returns an error or io.EOF, or when the context of the stream is done.
+// Sent and received messages are recorded as span events.
+func StreamClientInterceptor(opt ...Option) grpc.StreamClientInterceptor {
+	c := newConfig(opt)
+	metrics := newClientMetrics(c.meterProvider)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, span := c.startClientSpan(ctx, method, cc)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			c.finishClient(ctx, span, metrics, method, start, err)
+			return cs, err
+		}
+		stream := &clientStream{
+			ClientStream: cs,
+			desc:         desc,
+			span:         span,
+			done:         make(chan struct{}),
+			finish: func(err error) {
+				c.finishClient(ctx, span, metrics, method, start, err)
+			},
+		}
+		// end the span when the caller abandons the stream without reading it until the end.
+		go func() {
+			select {
+			case <-ctx.Done():
+				stream.end(status.FromContextError(ctx.Err()).Err())
+			case <-stream.done:
+			}
+		}()
+		return stream, nil
+	}
+}
+
+// startClientSpan starts a client span and injects its span context into the outgoing metadata.
+func (c *config) startClientSpan(ctx context.Context, method string, cc *grpc.ClientConn) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithAttributes(methodAttributes(method)...),
+		trace.WithAttributes(semconv.TelemetrySDKLanguageGo),
+		trace.WithSpanKind(trace.SpanKindClient),
+	}
+	if cc != nil {
+		opts = append(opts, trace.WithAttributes(targetAttributes(cc.Target())...))
+	}
+	// check for the config.attributes if present apply them to the trace.Span.
+	if len(c.attributes) > 0 {
+		opts = append(opts, trace.WithAttributes(c.attributes...))
+	}
+	ctx, span := c.tracer.Start(ctx, spanName(method), opts...)
+	return c.inject(ctx), span
+}
+
+// finishClient records the status on the span, ends it and records the duration of the call.
+func (c *config) finishClient(ctx context.Context, span trace.Span, metrics *rpcMetrics, method string, start time.Time, err error) {
+	s, _ := status.FromError(err)
+	code, description := spanStatus(s, false)
+	span.SetAttributes(statusCodeAttribute(s.Code()))
+	span.SetStatus(code, description)
+	span.End()
+
+	attributes := append(methodAttributes(method), statusCodeAttribute(s.Code()))
+	metrics.record(ctx, time.Since(start), attributes)
+}
+
+// clientStream wraps a grpc.ClientStream, it records the messages and ends the span once the stream is finished.
+type clientStream struct {
+	grpc.ClientStream
+	desc     *grpc.StreamDesc
+	span     trace.Span
+	finish   func(err error)
+	once     sync.Once
+	done     chan struct{}
+	sent     int
+	received int
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		messageEvent(s.span, semconv.RPCMessageTypeSent, s.sent)
+	}
+	// an error is also returned by RecvMsg, where the status of the stream is known.
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	default:
+		s.received++
+		messageEvent(s.span, semconv.RPCMessageTypeReceived, s.received)
+		// a stream without server streaming is finished once the single response has been received.
+		if !s.desc.ServerStreams {
+			s.end(nil)
+		}
+	}
+	return err
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.end(err)
+	}
+	return md, err
+}
+
+func (s *clientStream) CloseSend() error {
+	err := s.ClientStream.CloseSend()
+	if err != nil {
+		s.end(err)
+	}
+	return err
+}
+
+// end finishes the span once.
+func (s *clientStream) end(err error) {
+	s.once.Do(func() {
+		close(s.done)
+		s.finish(err)
+	})
+}
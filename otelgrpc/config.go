@@ -0,0 +1,98 @@
+// Copyright 2024 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgrpc
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// instrumentationName is used as the name of the trace.Tracer and metric.Meter.
+	instrumentationName = "github.com/vincentfree/opentelemetry/otelgrpc"
+	// version is used as the instrumentation version.
+	version = "0.1.0"
+)
+
+// Option takes a config struct and applies changes.
+// It can be passed to the interceptor functions to configure a config struct.
+type Option func(*config)
+
+// config contains all the configuration for the interceptors.
+type config struct {
+	tracer        trace.Tracer
+	propagator    propagation.TextMapPropagator
+	attributes    []attribute.KeyValue
+	meterProvider metric.MeterProvider
+}
+
+// newConfig applies the Option's to an empty config and sets default values for absent configuration.
+func newConfig(opt []Option) *config {
+	// initialize an empty config.
+	c := &config{}
+
+	// apply the configuration passed to the function.
+	for _, o := range opt {
+		o(c)
+	}
+	// check for the config.tracer if absent use a default value.
+	if c.tracer == nil {
+		c.tracer = otel.Tracer(instrumentationName, trace.WithInstrumentationVersion(version))
+	}
+	// check for the config.propagator if absent use a default value.
+	if c.propagator == nil {
+		c.propagator = otel.GetTextMapPropagator()
+	}
+	// check for the config.meterProvider if absent use a default value.
+	if c.meterProvider == nil {
+		c.meterProvider = otel.GetMeterProvider()
+	}
+	return c
+}
+
+// WithTracer is an Option to add a custom trace.Tracer to the interceptors.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// WithPropagator is an Option to add a custom propagation.TextMapPropagator to the interceptors.
+// The span context is propagated using the gRPC metadata of the call.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = p
+	}
+}
+
+// WithMeterProvider is an Option to add a custom metric.MeterProvider which is used to record the
+// rpc.server.duration and rpc.client.duration metrics.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithAttributes is an Option to add custom attributes to every span created by the interceptors.
+// Like the WithAttributes TraceOption of otelmiddleware, passing it more than once replaces the attributes,
+// only the attributes of the last call are applied.
+func WithAttributes(attributes ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attributes = attributes
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package otelgrpc provides interceptors for instrumenting gRPC servers and clients with Open Telemetry tracing and metrics support.
+
+The server interceptors extract the span context from the incoming metadata and start a trace.SpanKindServer span for every call,
+the client interceptors start a trace.SpanKindClient span and inject its span context into the outgoing metadata.
+The span is named after the full method, for example "grpc.health.v1.Health/Check", and carries the rpc.system,
+rpc.service, rpc.method and rpc.grpc.status_code attributes. Streams record every sent and received message as a span event.
+
+The gRPC status is mapped to the status of the span. A client span has an error status for every code other than OK,
+a server span only for the codes indicating a server error: Unknown, DeadlineExceeded, Unimplemented, Internal,
+Unavailable and DataLoss.
+
+Next to the trace.Span, the interceptors record the duration of every call in milliseconds as rpc.server.duration and rpc.client.duration.
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	)
+
+The Option functions mirror the TraceOption functions of otelmiddleware.
+
+Functions
+
+	func UnaryServerInterceptor(opt ...Option) grpc.UnaryServerInterceptor
+	func StreamServerInterceptor(opt ...Option) grpc.StreamServerInterceptor
+	func UnaryClientInterceptor(opt ...Option) grpc.UnaryClientInterceptor
+	func StreamClientInterceptor(opt ...Option) grpc.StreamClientInterceptor
+	func WithTracer(tracer trace.Tracer) Option
+	func WithPropagator(p propagation.TextMapPropagator) Option
+	func WithMeterProvider(provider metric.MeterProvider) Option
+	func WithAttributes(attributes ...attribute.KeyValue) Option
+
+Types
+
+	type Option func(*config)
+
+Structs
+
+	type config struct {
+		tracer trace.Tracer
+		propagator propagation.TextMapPropagator
+		attributes []attribute.KeyValue
+		meterProvider metric.MeterProvider
+	}
+*/
+package otelgrpc // import "github.com/vincentfree/opentelemetry/otelgrpc"
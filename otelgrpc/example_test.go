@@ -0,0 +1,55 @@
+// Copyright 2024 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgrpc_test
+
+import (
+	"github.com/vincentfree/opentelemetry/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func ExampleUnaryServerInterceptor() {
+	// instrument both unary and streaming calls of the server.
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	)
+	defer server.Stop()
+}
+
+func ExampleUnaryClientInterceptor() {
+	// instrument both unary and streaming calls of the client, the span context is sent as metadata.
+	conn, err := grpc.NewClient("localhost:50051",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(otelgrpc.UnaryClientInterceptor(otelgrpc.WithPropagator(otel.GetTextMapPropagator()))),
+		grpc.WithChainStreamInterceptor(otelgrpc.StreamClientInterceptor(otelgrpc.WithPropagator(otel.GetTextMapPropagator()))),
+	)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+}
+
+func ExampleWithAttributes() {
+	// add attributes to every span created by the interceptor.
+	interceptor := otelgrpc.UnaryServerInterceptor(
+		otelgrpc.WithTracer(otel.Tracer("example")),
+		otelgrpc.WithAttributes(attribute.String("deployment.environment", "production")),
+	)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptor))
+	defer server.Stop()
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testEnv holds an in-process health server and a client, both instrumented with the interceptors.
+type testEnv struct {
+	client   healthpb.HealthClient
+	recorder *tracetest.SpanRecorder
+	reader   *sdkmetric.ManualReader
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test-tracer")
+	reader := sdkmetric.NewManualReader()
+	opts := []Option{
+		WithTracer(tracer),
+		WithPropagator(propagation.TraceContext{}),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		WithAttributes(attribute.String("test", "value")),
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(opts...)),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(opts...)),
+	)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("test", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(opts...)),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(opts...)),
+	)
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &testEnv{client: healthpb.NewHealthClient(conn), recorder: recorder, reader: reader}
+}
+
+// spans waits until n spans have ended and returns the server and client span.
+func (e *testEnv) spans(t *testing.T, n int) (server, client sdktrace.ReadOnlySpan) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(e.recorder.Ended()) < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	spans := e.recorder.Ended()
+	if len(spans) != n {
+		t.Fatalf("expected %d ended spans, got: %d", n, len(spans))
+	}
+	for _, span := range spans {
+		switch span.SpanKind() {
+		case trace.SpanKindServer:
+			server = span
+		case trace.SpanKindClient:
+			client = span
+		}
+	}
+	if server == nil || client == nil {
+		t.Fatal("expected a server and a client span")
+	}
+	return server, client
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "test"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server, client := env.spans(t, 2)
+	if server.Name() != "grpc.health.v1.Health/Check" {
+		t.Errorf("unexpected span name: %s", server.Name())
+	}
+	if server.Parent().SpanID() != client.SpanContext().SpanID() || server.SpanContext().TraceID() != client.SpanContext().TraceID() {
+		t.Error("expected the server span to be a child of the client span")
+	}
+	expected := map[attribute.Key]attribute.Value{
+		"rpc.system":           attribute.StringValue("grpc"),
+		"rpc.service":          attribute.StringValue("grpc.health.v1.Health"),
+		"rpc.method":           attribute.StringValue("Check"),
+		"rpc.grpc.status_code": attribute.IntValue(int(codes.OK)),
+		"test":                 attribute.StringValue("value"),
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{server, client} {
+		for key, want := range expected {
+			if got, ok := attributeValue(span, key); !ok || got != want {
+				t.Errorf("expected attribute %s=%v on the %s span, got: %v", key, want.Emit(), span.SpanKind(), got.Emit())
+			}
+		}
+	}
+	if got, ok := attributeValue(client, "server.address"); !ok || got.AsString() != "bufnet" {
+		t.Errorf("expected attribute server.address=bufnet, got: %v", got.Emit())
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := env.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	for _, name := range []string{serverDurationName, clientDurationName} {
+		found := false
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if m.Name != name {
+					continue
+				}
+				found = true
+				if m.Unit != "ms" {
+					t.Errorf("expected unit ms for %s, got: %s", name, m.Unit)
+				}
+				if data, ok := m.Data.(metricdata.Histogram[float64]); !ok || len(data.DataPoints) != 1 || data.DataPoints[0].Count != 1 {
+					t.Errorf("expected one recorded value for %s", name)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("expected metric %s to be recorded", name)
+		}
+	}
+}
+
+func TestUnaryInterceptorsStatus(t *testing.T) {
+	env := newTestEnv(t)
+	_, err := env.client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected a NotFound error, got: %v", err)
+	}
+
+	server, client := env.spans(t, 2)
+	// NotFound is caused by the client, it is only an error on the client span.
+	if server.Status().Code != otelcodes.Unset {
+		t.Errorf("expected an unset status on the server span, got: %v", server.Status().Code)
+	}
+	if client.Status().Code != otelcodes.Error {
+		t.Errorf("expected an error status on the client span, got: %v", client.Status().Code)
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{server, client} {
+		if got, _ := attributeValue(span, "rpc.grpc.status_code"); got.AsInt64() != int64(codes.NotFound) {
+			t.Errorf("expected status code %d on the %s span, got: %d", codes.NotFound, span.SpanKind(), got.AsInt64())
+		}
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	env := newTestEnv(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := env.client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the watch stream never ends on its own, cancelling the context ends both spans.
+	cancel()
+
+	server, client := env.spans(t, 2)
+	if server.Parent().SpanID() != client.SpanContext().SpanID() {
+		t.Error("expected the server span to be a child of the client span")
+	}
+	if client.Status().Code != otelcodes.Error {
+		t.Errorf("expected an error status on the cancelled client span, got: %v", client.Status().Code)
+	}
+	if got, _ := attributeValue(client, "rpc.grpc.status_code"); got.AsInt64() != int64(codes.Canceled) {
+		t.Errorf("expected status code %d on the client span, got: %d", codes.Canceled, got.AsInt64())
+	}
+
+	messages := func(span sdktrace.ReadOnlySpan, messageType string) int {
+		count := 0
+		for _, event := range span.Events() {
+			for _, attr := range event.Attributes {
+				if attr.Key == "rpc.message.type" && attr.Value.AsString() == messageType {
+					count++
+				}
+			}
+		}
+		return count
+	}
+	if got := messages(server, "SENT"); got != 1 {
+		t.Errorf("expected 1 sent message event on the server span, got: %d", got)
+	}
+	if got := messages(client, "RECEIVED"); got != 1 {
+		t.Errorf("expected 1 received message event on the client span, got: %d", got)
+	}
+}
+
+func TestWithAttributesReplaces(t *testing.T) {
+	c := newConfig([]Option{
+		WithAttributes(attribute.String("first", "value")),
+		WithAttributes(attribute.String("second", "value")),
+	})
+	if len(c.attributes) != 1 || c.attributes[0].Key != "second" {
+		t.Errorf("expected only the attributes of the last WithAttributes, got: %v", c.attributes)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgrpc
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// metric names as defined by the OpenTelemetry RPC semantic conventions.
+	serverDurationName = "rpc.server.duration"
+	clientDurationName = "rpc.client.duration"
+)
+
+// rpcMetrics holds the instruments that are recorded for every call passing through an interceptor.
+type rpcMetrics struct {
+	duration metric.Float64Histogram
+}
+
+// newRPCMetrics creates the duration histogram with the given name using a metric.Meter from the metric.MeterProvider.
+// Errors returned while creating instruments are passed to the global otel error handler.
+func newRPCMetrics(mp metric.MeterProvider, name, description string) *rpcMetrics {
+	meter := mp.Meter(instrumentationName, metric.WithInstrumentationVersion(version))
+	duration, err := meter.Float64Histogram(name,
+		metric.WithDescription(description),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return &rpcMetrics{duration: duration}
+}
+
+// newServerMetrics creates the rpc.server.duration instrument.
+func newServerMetrics(mp metric.MeterProvider) *rpcMetrics {
+	return newRPCMetrics(mp, serverDurationName, "Duration of inbound RPCs.")
+}
+
+// newClientMetrics creates the rpc.client.duration instrument.
+func newClientMetrics(mp metric.MeterProvider) *rpcMetrics {
+	return newRPCMetrics(mp, clientDurationName, "Duration of outbound RPCs.")
+}
+
+// record records the duration of a finished call in milliseconds.
+func (m *rpcMetrics) record(ctx context.Context, elapsed time.Duration, attributes []attribute.KeyValue) {
+	m.duration.Record(ctx, float64(elapsed)/float64(time.Millisecond), metric.WithAttributeSet(attribute.NewSet(attributes...)))
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgrpc
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor which starts a server trace.Span for every call.
+// The span context is extracted from the incoming metadata and the duration is recorded as rpc.server.duration.
+func UnaryServerInterceptor(opt ...Option) grpc.UnaryServerInterceptor {
+	c := newConfig(opt)
+	metrics := newServerMetrics(c.meterProvider)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, span := c.startServerSpan(ctx, info.FullMethod)
+		res, err := handler(ctx, req)
+		c.finishServer(ctx, span, metrics, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+// StreamServerInterceptor returns a grpc.StreamServerInterceptor which starts a server trace.Span for every stream.
+// The span ends when the handler returns, sent and received messages are recorded as span events.
+func StreamServerInterceptor(opt ...Option) grpc.StreamServerInterceptor {
+	c := newConfig(opt)
+	metrics := newServerMetrics(c.meterProvider)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, span := c.startServerSpan(ss.Context(), info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, span: span})
+		c.finishServer(ctx, span, metrics, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// startServerSpan extracts the span context from the incoming metadata and starts a server span.
+func (c *config) startServerSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	ctx = c.extract(ctx)
+	opts := []trace.SpanStartOption{
+		trace.WithAttributes(methodAttributes(fullMethod)...),
+		trace.WithAttributes(peerAttributes(ctx)...),
+		trace.WithAttributes(semconv.TelemetrySDKLanguageGo),
+		trace.WithSpanKind(trace.SpanKindServer),
+	}
+	// check for the config.attributes if present apply them to the trace.Span.
+	if len(c.attributes) > 0 {
+		opts = append(opts, trace.WithAttributes(c.attributes...))
+	}
+	return c.tracer.Start(ctx, spanName(fullMethod), opts...)
+}
+
+// finishServer records the status on the span, ends it and records the duration of the call.
+func (c *config) finishServer(ctx context.Context, span trace.Span, metrics *rpcMetrics, fullMethod string, start time.Time, err error) {
+	s, _ := status.FromError(err)
+	code, description := spanStatus(s, true)
+	span.SetAttributes(statusCodeAttribute(s.Code()))
+	span.SetStatus(code, description)
+	span.End()
+
+	attributes := append(methodAttributes(fullMethod), statusCodeAttribute(s.Code()))
+	metrics.record(ctx, time.Since(start), attributes)
+}
+
+// serverStream wraps a grpc.ServerStream, it returns the context carrying the span and records the messages.
+type serverStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	span     trace.Span
+	sent     int
+	received int
+}
+
+// Context returns the context of the stream carrying the server span.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		messageEvent(s.span, semconv.RPCMessageTypeSent, s.sent)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+		messageEvent(s.span, semconv.RPCMessageTypeReceived, s.received)
+	}
+	return err
+}
+
+// messageEvent adds a message event to the span, the id is the sequence number of the message within the stream.
+func messageEvent(span trace.Span, messageType attribute.KeyValue, id int) {
+	span.AddEvent("message", trace.WithAttributes(messageType, semconv.RPCMessageID(id)))
+}
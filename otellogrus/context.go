@@ -0,0 +1,80 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otellogrus
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDBaggageKey is the baggage member carrying the request ID, it matches otelmiddleware.RequestIDBaggageKey.
+const requestIDBaggageKey = "request.id"
+
+// entryKey is the context.Context key under which a logrus.Entry is stored.
+type entryKey struct{}
+
+// NewContext returns a copy of ctx which carries the logrus.Entry, it can be retrieved using FromContext.
+func NewContext(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// FromContext returns the logrus.Entry stored in ctx by NewContext or ContextWithTracing.
+// When ctx does not carry an entry, an entry of the logrus standard logger is returned.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(logrus.StandardLogger()).WithContext(ctx)
+}
+
+// ContextWithTracing returns a copy of ctx which carries a logrus.Entry of the Logger.
+// The entry contains the trace context of the span, the attributes and the request ID in the baggage of ctx.
+// The method matches the otelmiddleware.LoggerFactory signature, so a request-scoped logger can be placed in the request context.
+func (l Logger) ContextWithTracing(ctx context.Context, span trace.Span, attributes []attribute.KeyValue) context.Context {
+	return NewContext(ctx, withRequestID(ctx, l.WithTracingContextAndAttributes(span, attributes).WithContext(ctx)))
+}
+
+// LogAccess writes an access log line with the trace context of the span and the attributes.
+// The method implements the otelmiddleware.AccessLogger interface, the slog.Level is mapped to the closest logrus.Level.
+func (l Logger) LogAccess(ctx context.Context, level slog.Level, msg string, span trace.Span, attributes []attribute.KeyValue) {
+	withRequestID(ctx, l.WithTracingContextAndAttributes(span, attributes).WithContext(ctx)).Log(logrusLevel(level), msg)
+}
+
+// withRequestID adds the request ID carried in the baggage of ctx, as set by the otelmiddleware.WithRequestID option.
+func withRequestID(ctx context.Context, entry *logrus.Entry) *logrus.Entry {
+	if id := baggage.FromContext(ctx).Member(requestIDBaggageKey).Value(); id != "" {
+		return entry.WithField(_requestId, id)
+	}
+	return entry
+}
+
+// logrusLevel maps a slog.Level to a logrus.Level.
+func logrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level >= slog.LevelError:
+		return logrus.ErrorLevel
+	case level >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level >= slog.LevelInfo:
+		return logrus.InfoLevel
+	default:
+		return logrus.DebugLevel
+	}
+}
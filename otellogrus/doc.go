@@ -27,6 +27,7 @@ Functions
 	func SetLogOptions(options ...LogOption)
 	func WithTraceID(traceID string) LogOption
 	func WithSpanID(spanID string) LogOption
+	func WithRequestID(requestID string) LogOption
 	func WithServiceName(serviceName string) LogOption
 	func WithAttributePrefix(prefix string) LogOption
 	func WithAttributes(attributes ...attribute.KeyValue) LogOption
@@ -37,6 +38,10 @@ Functions
 	func New(options ...LoggerOption) *Logger
 	func (l Logger) WithTracingContext(span trace.Span, err ...error) *logrus.Entry
 	func (l Logger) WithTracingContextAndAttributes(span trace.Span, attributes []attribute.KeyValue, err ...error) *logrus.Entry
+	func (l Logger) ContextWithTracing(ctx context.Context, span trace.Span, attributes []attribute.KeyValue) context.Context
+	func (l Logger) LogAccess(ctx context.Context, level slog.Level, msg string, span trace.Span, attributes []attribute.KeyValue)
+	func NewContext(ctx context.Context, entry *logrus.Entry) context.Context
+	func FromContext(ctx context.Context) *logrus.Entry
 
 Types
 
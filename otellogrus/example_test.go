@@ -136,3 +136,12 @@ func ExampleLogger_WithTracingContextAndAttributes() {
 	logger.WithTracingContextAndAttributes(span, attributes, err).Error("example error message")
 
 }
+
+func ExampleLogger_ContextWithTracing() {
+	tracer := otel.Tracer("otellogrus/example")
+	ctx, span := tracer.Start(context.Background(), "example-span")
+	logger := otellogrus.New()
+	// store a logger carrying the trace context, this method can be passed to otelmiddleware.WithLoggerFactory.
+	ctx = logger.ContextWithTracing(ctx, span, nil)
+	otellogrus.FromContext(ctx).Info("handling request")
+}
@@ -32,6 +32,7 @@ type logConfig struct {
 	serviceName     string
 	traceId         string
 	spanId          string
+	requestId       string
 	attributePrefix string
 }
 
@@ -49,6 +50,8 @@ var (
 	_traceId = "traceID"
 	// _spanId has a default span ID key in the logs
 	_spanId = "spanID"
+	// _requestId has a default request ID key in the logs
+	_requestId = "requestID"
 	// _serviceName is empty by default, when no value is set the service name won't be used with a default in the logs
 	_serviceName string
 	// _attributes contains a global set of attribute.KeyValue's that will be added to very structured log. when the slice is empty they won't be added
@@ -75,6 +78,10 @@ func SetLogOptions(options ...LogOption) {
 		_traceId = config.spanId
 	}
 
+	if config.requestId != "" {
+		_requestId = config.requestId
+	}
+
 	if config.serviceName != "" {
 		_serviceName = config.serviceName
 	}
@@ -103,6 +110,13 @@ func WithSpanID(spanID string) LogOption {
 	}
 }
 
+// WithRequestID overwrites the default 'requestID' field in the structured logs with your own key
+func WithRequestID(requestID string) LogOption {
+	return func(c *logConfig) {
+		c.requestId = requestID
+	}
+}
+
 // WithServiceName adds a service name to the field 'service.name' in your structured logs
 func WithServiceName(serviceName string) LogOption {
 	return func(c *logConfig) {
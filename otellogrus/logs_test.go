@@ -21,8 +21,10 @@ import (
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"golang.org/x/exp/constraints"
 	"io"
+	"log/slog"
 	"os"
 	"reflect"
 	"testing"
@@ -299,3 +301,47 @@ func idCheck(t *testing.T, name string, value any, length int) {
 		t.Errorf("%s should be in the log", name)
 	}
 }
+
+func TestLogger_ContextWithTracing(t *testing.T) {
+	_, span := otel.Tracer("test").Start(context.Background(), "serviceName")
+	out := captureWithLogger(t, func(logger *Logger) {
+		ctx := logger.ContextWithTracing(context.Background(), span, []attribute.KeyValue{attribute.String("http.route", "/users/{id}")})
+		FromContext(ctx).Info("test")
+	})
+	data := logToMap(t, out)
+
+	idCheck(t, "traceID", data["traceID"], 32)
+	idCheck(t, "spanID", data["spanID"], 16)
+	attributeCheck(t, data["trace.attribute.http.route"], "/users/{id}")
+}
+
+func TestLogger_LogAccess(t *testing.T) {
+	_, span := otel.Tracer("test").Start(context.Background(), "serviceName")
+	out := captureWithLogger(t, func(logger *Logger) {
+		logger.LogAccess(context.Background(), slog.LevelWarn, "request completed", span, []attribute.KeyValue{attribute.Int("http.response.status_code", 404)})
+	})
+	data := logToMap(t, out)
+
+	attributeCheck(t, data["level"], "warning")
+	attributeCheck(t, data["msg"], "request completed")
+	idCheck(t, "traceID", data["traceID"], 32)
+	idCheck(t, "spanID", data["spanID"], 16)
+	attributeCheck(t, data["trace.attribute.http.response.status_code"], float64(404))
+}
+
+func TestLogger_RequestID(t *testing.T) {
+	_, span := otel.Tracer("test").Start(context.Background(), "serviceName")
+	member, _ := baggage.NewMemberRaw(requestIDBaggageKey, "0190b5a4-6b2c-7d1e-8f00-000000000001")
+	bag, _ := baggage.New(member)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	out := captureWithLogger(t, func(logger *Logger) {
+		FromContext(logger.ContextWithTracing(ctx, span, nil)).Info("test")
+	})
+	attributeCheck(t, logToMap(t, out)["requestID"], "0190b5a4-6b2c-7d1e-8f00-000000000001")
+
+	out = captureWithLogger(t, func(logger *Logger) {
+		logger.LogAccess(ctx, slog.LevelInfo, "request completed", span, nil)
+	})
+	attributeCheck(t, logToMap(t, out)["requestID"], "0190b5a4-6b2c-7d1e-8f00-000000000001")
+}
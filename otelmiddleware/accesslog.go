@@ -0,0 +1,169 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// accessLogMessage is the message of every access log line.
+const accessLogMessage = "request completed"
+
+// AccessLogger writes an access log line, the trace context of the span is added to the log.
+// The otelslog, otelzerolog and otellogrus loggers implement this interface, a slog.Level is mapped to the closest level of the logger.
+type AccessLogger interface {
+	LogAccess(ctx context.Context, level slog.Level, msg string, span trace.Span, attributes []attribute.KeyValue)
+}
+
+// AccessLogOption takes an accessLogConfig struct and applies changes.
+// It can be passed to the AccessLog function to configure the access log.
+type AccessLogOption func(*accessLogConfig)
+
+// accessLogConfig contains the configuration for the AccessLog middleware.
+type accessLogConfig struct {
+	// levels holds the level per status class, levels[4] is used for 4xx responses.
+	levels [6]slog.Level
+	// configured marks the status classes of which the level was set using WithAccessLogLevel.
+	configured         [6]bool
+	successSampleRatio float64
+}
+
+// newAccessLogConfig applies the AccessLogOption's and sets default values for absent configuration.
+func newAccessLogConfig(opts []AccessLogOption) *accessLogConfig {
+	config := &accessLogConfig{
+		levels:             [6]slog.Level{slog.LevelInfo, slog.LevelInfo, slog.LevelInfo, slog.LevelInfo, slog.LevelWarn, slog.LevelError},
+		successSampleRatio: 1,
+	}
+	for _, o := range opts {
+		o(config)
+	}
+	return config
+}
+
+// WithAccessLogLevel sets the level used for a status class, for example WithAccessLogLevel(4, slog.LevelInfo) logs 4xx responses as info.
+// By default 1xx, 2xx and 3xx responses are logged as info, 4xx as warning and 5xx as error.
+// The configured level is always used for its status class, it is never lowered. A response below 5xx which the StatusClassifier
+// classified as failed is logged at least at the warning level, a 5xx response classified as not failed is logged at the
+// warning level unless the level of 5xx responses is configured.
+func WithAccessLogLevel(statusClass int, level slog.Level) AccessLogOption {
+	return func(c *accessLogConfig) {
+		if statusClass >= 1 && statusClass <= 5 {
+			c.levels[statusClass] = level
+			c.configured[statusClass] = true
+		}
+	}
+}
+
+// WithSuccessSampleRatio sets the ratio of successful requests, with a status below 400, that are logged, between 0 and 1.
+// Failed requests are always logged. The default is 1.
+func WithSuccessSampleRatio(ratio float64) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.successSampleRatio = ratio
+	}
+}
+
+// level returns the level of the class of the status code. A response below 5xx which the StatusClassifier classified as
+// failed is raised to at least the warning level, the default level of a 5xx response classified as not failed is lowered
+// to the warning level. A level set using WithAccessLogLevel is never lowered.
+func (c *accessLogConfig) level(statusCode int, failed bool) slog.Level {
+	class := statusCode / 100
+	if class < 1 || class > 5 {
+		class = 5
+	}
+	level := c.levels[class]
+	switch {
+	case failed && class != 5:
+		return max(level, slog.LevelWarn)
+	case !failed && class == 5 && !c.configured[class]:
+		return min(level, slog.LevelWarn)
+	}
+	return level
+}
+
+// sampled reports whether a request finished with the status code should be logged, failed requests are always logged.
+func (c *accessLogConfig) sampled(statusCode int, failed bool) bool {
+	if failed || statusCode >= 400 || c.successSampleRatio >= 1 {
+		return true
+	}
+	return rand.Float64() < c.successSampleRatio
+}
+
+// AccessLog returns a middleware which writes one access log line per request using the AccessLogger.
+// The line contains the method, route, status code, bytes written, duration and client address of the request,
+// the trace and span ID are taken from the span in the request context. To correlate the log with the span
+// created by TraceWithOptions, the AccessLog middleware is placed after it. The StatusClassifier configured using
+// WithStatusClassifier is then also used to classify failed requests, which are logged including the error.type:
+//
+//	handler := otelmiddleware.Trace(otelmiddleware.AccessLog(otelslog.New())(mux))
+func AccessLog(logger AccessLogger, opt ...AccessLogOption) func(next http.Handler) http.Handler {
+	config := newAccessLogConfig(opt)
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			// reuse the writer of TraceWithOptions, it already counts the written bytes.
+			wrapperRes, ok := w.(WrapResponseWriter)
+			if !ok {
+				wrapperRes = NewWrapResponseWriter(w, r.ProtoMajor)
+				expectUpgrade(wrapperRes, r)
+			}
+
+			next.ServeHTTP(wrapperRes, r)
+
+			statusCode := wrapperRes.Status()
+			if statusCode == 0 && !hijacked(wrapperRes) {
+				// the handler did not write a response, the http.Server answers with 200 OK.
+				statusCode = http.StatusOK
+			}
+			ctx := r.Context()
+			code, _, errorType := statusClassifierFromContext(ctx)(statusCode, r)
+			failed := code == codes.Error
+			if !config.sampled(statusCode, failed) {
+				return
+			}
+			attributes := accessLogAttributes(r, statusCode, wrapperRes.BytesWritten(), time.Since(start))
+			if failed && errorType != "" {
+				attributes = append(attributes, semconv.ErrorTypeKey.String(errorType))
+			}
+			logger.LogAccess(ctx, config.level(statusCode, failed), accessLogMessage, trace.SpanFromContext(ctx), attributes)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// accessLogAttributes returns the attributes of an access log line.
+func accessLogAttributes(r *http.Request, statusCode, bytesWritten int, elapsed time.Duration) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(r.Method)}
+	if route := RouteFromRequest(r); route != "" {
+		attributes = append(attributes, semconv.HTTPRoute(route))
+	}
+	attributes = append(attributes,
+		semconv.HTTPResponseStatusCode(statusCode),
+		semconv.HTTPResponseBodySize(bytesWritten),
+		attribute.Float64(serverRequestDurationName, elapsed.Seconds()),
+	)
+	if client := ClientAddressFromRequest(r); client != "" {
+		attributes = append(attributes, semconv.ClientAddress(client))
+	}
+	return attributes
+}
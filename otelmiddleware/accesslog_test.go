@@ -0,0 +1,155 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type accessLogEntry struct {
+	level       slog.Level
+	spanContext trace.SpanContext
+	attributes  attribute.Set
+}
+
+type testAccessLogger struct {
+	entries []accessLogEntry
+}
+
+func (l *testAccessLogger) LogAccess(_ context.Context, level slog.Level, _ string, span trace.Span, attributes []attribute.KeyValue) {
+	l.entries = append(l.entries, accessLogEntry{level: level, spanContext: span.SpanContext(), attributes: attribute.NewSet(attributes...)})
+}
+
+func TestAccessLog(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	logger := &testAccessLogger{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+	handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")))(AccessLog(logger)(mux))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/123", nil))
+
+	if len(logger.entries) != 1 {
+		t.Fatalf("expected 1 access log line, got: %d", len(logger.entries))
+	}
+	entry := logger.entries[0]
+	if entry.level != slog.LevelInfo {
+		t.Errorf("expected level %v, got: %v", slog.LevelInfo, entry.level)
+	}
+	if spans := recorder.Ended(); len(spans) != 1 || !entry.spanContext.Equal(spans[0].SpanContext()) {
+		t.Errorf("expected the access log to carry the span context of the request")
+	}
+	expected := map[attribute.Key]attribute.Value{
+		"http.request.method":       attribute.StringValue("GET"),
+		"http.route":                attribute.StringValue("/users/{id}"),
+		"http.response.status_code": attribute.IntValue(200),
+		"http.response.body.size":   attribute.IntValue(5),
+		"client.address":            attribute.StringValue("192.0.2.1"),
+	}
+	for key, want := range expected {
+		if got, ok := entry.attributes.Value(key); !ok || got != want {
+			t.Errorf("expected attribute %s=%v, got: %v", key, want.Emit(), got.Emit())
+		}
+	}
+	if _, ok := entry.attributes.Value(serverRequestDurationName); !ok {
+		t.Errorf("expected attribute %s to be present", serverRequestDurationName)
+	}
+}
+
+func TestAccessLogLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		opts   []AccessLogOption
+		want   slog.Level
+	}{
+		{name: "success", status: http.StatusNoContent, want: slog.LevelInfo},
+		{name: "redirect", status: http.StatusFound, want: slog.LevelInfo},
+		{name: "client error", status: http.StatusNotFound, want: slog.LevelWarn},
+		{name: "server error", status: http.StatusBadGateway, want: slog.LevelError},
+		{name: "custom level", status: http.StatusNotFound, opts: []AccessLogOption{WithAccessLogLevel(4, slog.LevelInfo)}, want: slog.LevelInfo},
+		{name: "custom success level", status: http.StatusOK, opts: []AccessLogOption{WithAccessLogLevel(2, slog.LevelError)}, want: slog.LevelError},
+		{name: "custom server error level", status: http.StatusBadGateway, opts: []AccessLogOption{WithAccessLogLevel(5, slog.LevelInfo)}, want: slog.LevelInfo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &testAccessLogger{}
+			handler := AccessLog(logger, tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			if len(logger.entries) != 1 {
+				t.Fatalf("expected 1 access log line, got: %d", len(logger.entries))
+			}
+			if logger.entries[0].level != tt.want {
+				t.Errorf("expected level %v, got: %v", tt.want, logger.entries[0].level)
+			}
+		})
+	}
+}
+
+func TestAccessLogLevelFailed(t *testing.T) {
+	config := newAccessLogConfig([]AccessLogOption{WithAccessLogLevel(2, slog.LevelError), WithAccessLogLevel(5, slog.LevelInfo)})
+	tests := []struct {
+		name   string
+		status int
+		want   slog.Level
+	}{
+		// a failed response below 5xx is raised to the warning level.
+		{name: "client error", status: http.StatusConflict, want: slog.LevelWarn},
+		// a configured level above the warning level is kept.
+		{name: "success", status: http.StatusOK, want: slog.LevelError},
+		// the configured level of 5xx responses is used as is.
+		{name: "server error", status: http.StatusInternalServerError, want: slog.LevelInfo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.level(tt.status, true); got != tt.want {
+				t.Errorf("expected level %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithSuccessSampleRatio(t *testing.T) {
+	logger := &testAccessLogger{}
+	status := http.StatusOK
+	handler := AccessLog(logger, WithSuccessSampleRatio(0))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(logger.entries) != 0 {
+		t.Errorf("expected successful requests not to be logged, got: %d lines", len(logger.entries))
+	}
+
+	status = http.StatusInternalServerError
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(logger.entries) != 1 {
+		t.Errorf("expected failed requests to be logged, got: %d lines", len(logger.entries))
+	}
+}
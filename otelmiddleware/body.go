@@ -0,0 +1,178 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"path"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// requestBodyEvent and responseBodyEvent are the names of the span events containing a captured body.
+	requestBodyEvent  = "http.request.body"
+	responseBodyEvent = "http.response.body"
+	// defaultBodyMaxBytes is the default maximum number of captured bytes per body.
+	defaultBodyMaxBytes = 4096
+)
+
+// defaultBodyContentTypes are the media types captured by default, the entries use the syntax of path.Match.
+var defaultBodyContentTypes = []string{"application/json", "application/*+json", "text/*"}
+
+// BodyCaptureOption takes a bodyCaptureConfig struct and applies changes.
+// It can be passed to the WithBodyCapture function to configure the capture of request and response bodies.
+type BodyCaptureOption func(*bodyCaptureConfig)
+
+// bodyCaptureConfig contains the configuration for capturing request and response bodies.
+type bodyCaptureConfig struct {
+	maxBytes     int
+	contentTypes []string
+	sampleRatio  float64
+	redactor     func(contentType string, body []byte) []byte
+}
+
+// newBodyCaptureConfig applies the BodyCaptureOption's and sets default values for absent configuration.
+func newBodyCaptureConfig(opts []BodyCaptureOption) *bodyCaptureConfig {
+	config := &bodyCaptureConfig{maxBytes: defaultBodyMaxBytes, sampleRatio: 1}
+	for _, o := range opts {
+		o(config)
+	}
+	if config.maxBytes <= 0 {
+		config.maxBytes = defaultBodyMaxBytes
+	}
+	if len(config.contentTypes) == 0 {
+		config.contentTypes = defaultBodyContentTypes
+	}
+	return config
+}
+
+// WithBodyMaxBytes sets the maximum number of captured bytes per body, larger bodies are truncated. The default is 4096.
+func WithBodyMaxBytes(maxBytes int) BodyCaptureOption {
+	return func(c *bodyCaptureConfig) {
+		c.maxBytes = maxBytes
+	}
+}
+
+// WithBodyContentTypes sets the media types of which the body is captured, for example "application/json" or "text/*".
+// The entries use the syntax of path.Match. By default JSON and text bodies are captured.
+func WithBodyContentTypes(contentTypes ...string) BodyCaptureOption {
+	return func(c *bodyCaptureConfig) {
+		c.contentTypes = append(c.contentTypes, contentTypes...)
+	}
+}
+
+// WithBodySampleRatio sets the ratio of requests of which the bodies are captured, between 0 and 1. The default is 1.
+func WithBodySampleRatio(ratio float64) BodyCaptureOption {
+	return func(c *bodyCaptureConfig) {
+		c.sampleRatio = ratio
+	}
+}
+
+// WithBodyRedactor sets a function which is called with the content type and the captured body before it is recorded,
+// it returns the body with sensitive information removed.
+func WithBodyRedactor(redactor func(contentType string, body []byte) []byte) BodyCaptureOption {
+	return func(c *bodyCaptureConfig) {
+		c.redactor = redactor
+	}
+}
+
+// sampled reports whether the bodies of the current request should be captured.
+func (c *bodyCaptureConfig) sampled() bool {
+	return c.sampleRatio >= 1 || rand.Float64() < c.sampleRatio
+}
+
+// allowed reports whether the media type of the content type is part of the allow-list.
+func (c *bodyCaptureConfig) allowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range c.contentTypes {
+		if ok, _ := path.Match(pattern, mediaType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// captureRequest reads up to maxBytes of the request body and replaces the body so the handler can still read it in full.
+// It returns the captured bytes and whether the body got truncated.
+func (c *bodyCaptureConfig) captureRequest(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody || !c.allowed(r.Header.Get("Content-Type")) {
+		return nil, false
+	}
+	// read one byte more than allowed to find out if the body is truncated.
+	buf, err := io.ReadAll(io.LimitReader(r.Body, int64(c.maxBytes)+1))
+	r.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(buf), &errReader{err: err}, r.Body), Closer: r.Body}
+	if len(buf) > c.maxBytes {
+		return buf[:c.maxBytes], true
+	}
+	return buf, false
+}
+
+// record adds the captured body as a span event, the body is passed to the redactor first.
+func (c *bodyCaptureConfig) record(span trace.Span, event, contentType string, body []byte, truncated bool) {
+	if len(body) == 0 {
+		return
+	}
+	if c.redactor != nil {
+		body = c.redactor(contentType, body)
+	}
+	span.AddEvent(event, trace.WithAttributes(
+		attribute.String(event+".content", string(body)),
+		attribute.Bool(event+".truncated", truncated),
+	))
+}
+
+// replayBody replays the captured part of the request body, followed by the remainder of the original body.
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader returns the error which occurred while capturing the request body, this way the handler still receives it.
+type errReader struct {
+	err error
+}
+
+func (e *errReader) Read([]byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	return 0, io.EOF
+}
+
+// limitedBuffer keeps the first max bytes written to it, it never returns an error so it can be used with WrapResponseWriter.Tee.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := l.max - l.buf.Len(); remaining < len(p) {
+		l.truncated = true
+		l.buf.Write(p[:max(remaining, 0)])
+		return len(p), nil
+	}
+	l.buf.Write(p)
+	return len(p), nil
+}
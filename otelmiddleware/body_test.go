@@ -0,0 +1,179 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestBodyCapture(t *testing.T) {
+	const payload = `{"name":"test","password":"secret"}`
+	testCases := []struct {
+		desc          string
+		options       []BodyCaptureOption
+		contentType   string
+		wantRequest   string
+		wantResponse  string
+		wantTruncated bool
+	}{
+		{
+			desc:         "json bodies",
+			contentType:  "application/json; charset=utf-8",
+			wantRequest:  payload,
+			wantResponse: payload,
+		},
+		{
+			desc:          "truncated bodies",
+			options:       []BodyCaptureOption{WithBodyMaxBytes(8)},
+			contentType:   "application/json",
+			wantRequest:   payload[:8],
+			wantResponse:  payload[:8],
+			wantTruncated: true,
+		},
+		{
+			desc:        "content type not allowed",
+			contentType: "application/octet-stream",
+		},
+		{
+			desc:        "not sampled",
+			options:     []BodyCaptureOption{WithBodySampleRatio(0)},
+			contentType: "application/json",
+		},
+		{
+			desc: "redacted bodies",
+			options: []BodyCaptureOption{WithBodyRedactor(func(_ string, body []byte) []byte {
+				return bytes.ReplaceAll(body, []byte("secret"), []byte("***"))
+			})},
+			contentType:  "application/json",
+			wantRequest:  strings.ReplaceAll(payload, "secret", "***"),
+			wantResponse: strings.ReplaceAll(payload, "secret", "***"),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			var received string
+			handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")), WithBodyCapture(tC.options...))(
+				testHandler(func(w http.ResponseWriter, r *http.Request) {
+					body, _ := io.ReadAll(r.Body)
+					received = string(body)
+					w.Header().Set("Content-Type", tC.contentType)
+					_, _ = w.Write(body)
+				}))
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+			r.Header.Set("Content-Type", tC.contentType)
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, r)
+
+			if received != payload || res.Body.String() != payload {
+				t.Errorf("expected the handler to receive and echo the full body, got: '%s' and '%s'", received, res.Body.String())
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ended span, got: %d", len(spans))
+			}
+			got := map[string]string{}
+			for _, event := range spans[0].Events() {
+				for _, attr := range event.Attributes {
+					switch string(attr.Key) {
+					case event.Name + ".content":
+						got[event.Name] = attr.Value.AsString()
+					case event.Name + ".truncated":
+						if attr.Value.AsBool() != tC.wantTruncated {
+							t.Errorf("expected %s to be %t", attr.Key, tC.wantTruncated)
+						}
+					}
+				}
+			}
+			if got[requestBodyEvent] != tC.wantRequest {
+				t.Errorf("expected request body '%s', got: '%s'", tC.wantRequest, got[requestBodyEvent])
+			}
+			if got[responseBodyEvent] != tC.wantResponse {
+				t.Errorf("expected response body '%s', got: '%s'", tC.wantResponse, got[responseBodyEvent])
+			}
+		})
+	}
+}
+
+func TestBodyCaptureReadFrom(t *testing.T) {
+	const payload = "0123456789"
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+
+	handler := TraceWithOptions(
+		WithTracer(provider.Tracer("test-tracer")),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		WithBodyCapture(),
+	)(testHandler(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		// io.Copy uses the io.ReaderFrom of the response writer, like http.ServeContent does.
+		_, _ = io.Copy(w, struct{ io.Reader }{strings.NewReader(payload)})
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	res, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if string(body) != payload {
+		t.Errorf("expected response body '%s', got: '%s'", payload, body)
+	}
+
+	spans := waitForSpans(recorder, 1)
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 ended span, got: %d", len(spans))
+	}
+	var captured string
+	for _, event := range spans[0].Events() {
+		if event.Name == responseBodyEvent {
+			captured = attributeValue(event.Attributes, responseBodyEvent+".content").AsString()
+		}
+	}
+	if captured != payload {
+		t.Errorf("expected captured response body '%s', got: '%s'", payload, captured)
+	}
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics failed due to: %v", err)
+	}
+	var size int64 = -1
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if histogram, ok := m.Data.(metricdata.Histogram[int64]); ok && m.Name == serverResponseBodySizeName {
+			size = histogram.DataPoints[0].Sum
+		}
+	}
+	if size != int64(len(payload)) {
+		t.Errorf("expected a response body size of %d, got %d", len(payload), size)
+	}
+}
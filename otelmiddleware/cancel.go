@@ -0,0 +1,85 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// cancellationKey records why the request context was canceled while the request was served, client or timeout.
+	cancellationKey = attribute.Key("http.request.cancellation")
+	// canceledEvent marks the moment the request context was canceled.
+	canceledEvent = "http.request.canceled"
+	// cancellationClient and cancellationTimeout are the values of cancellationKey.
+	cancellationClient  = "client"
+	cancellationTimeout = "timeout"
+	// clientClosedRequest is the error.type of a request canceled by the client, after the 499 status used by nginx.
+	clientClosedRequest = "499"
+)
+
+// cancellationWatcher watches the request context while the request is served, to tell a client which went away
+// from a deadline which fired, for example the deadline of a http.TimeoutHandler placed before the middleware.
+type cancellationWatcher struct {
+	stop func() bool
+	done chan struct{}
+	at   time.Time
+}
+
+// watchCancellation starts watching ctx, finish must be called once the request is served.
+func watchCancellation(ctx context.Context) *cancellationWatcher {
+	w := &cancellationWatcher{done: make(chan struct{})}
+	w.stop = context.AfterFunc(ctx, func() {
+		w.at = time.Now()
+		close(w.done)
+	})
+	return w
+}
+
+// finish stops watching and returns the reason ctx was canceled while the request was served, empty when it was not canceled.
+func (w *cancellationWatcher) finish(ctx context.Context) string {
+	if w.stop() {
+		return ""
+	}
+	<-w.done
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return cancellationTimeout
+	}
+	return cancellationClient
+}
+
+// record adds the cancellation attribute and an event at the moment ctx was canceled to the span.
+func (w *cancellationWatcher) record(ctx context.Context, span trace.Span, reason string) {
+	span.SetAttributes(cancellationKey.String(reason))
+	span.AddEvent(canceledEvent, trace.WithTimestamp(w.at), trace.WithAttributes(
+		cancellationKey.String(reason),
+		semconv.ExceptionMessage(context.Cause(ctx).Error()),
+	))
+}
+
+// cancellationStatus returns the span status and error.type of a canceled request.
+func cancellationStatus(reason string) (codes.Code, string, string) {
+	if reason == cancellationTimeout {
+		return codes.Error, "request deadline exceeded", cancellationTimeout
+	}
+	return codes.Error, "client closed request", clientClosedRequest
+}
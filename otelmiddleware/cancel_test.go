@@ -0,0 +1,138 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestRequestCancellation(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		wrap       func(http.Handler) http.Handler
+		handler    func(cancel context.CancelFunc) http.HandlerFunc
+		reason     string
+		errorType  string
+		statusCode codes.Code
+	}{
+		{
+			desc: "client disconnect",
+			wrap: func(h http.Handler) http.Handler { return h },
+			handler: func(cancel context.CancelFunc) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					// the http.Server cancels the request context once the client goes away.
+					cancel()
+					<-r.Context().Done()
+				}
+			},
+			reason:     cancellationClient,
+			errorType:  clientClosedRequest,
+			statusCode: codes.Error,
+		},
+		{
+			desc: "server timeout",
+			wrap: func(h http.Handler) http.Handler { return http.TimeoutHandler(h, 10*time.Millisecond, "timeout") },
+			handler: func(context.CancelFunc) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					<-r.Context().Done()
+				}
+			},
+			reason:     cancellationTimeout,
+			errorType:  cancellationTimeout,
+			statusCode: codes.Error,
+		},
+		{
+			desc: "not canceled",
+			wrap: func(h http.Handler) http.Handler { return h },
+			handler: func(context.CancelFunc) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {}
+			},
+			statusCode: codes.Unset,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			reader := sdkmetric.NewManualReader()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			handler := tC.wrap(TraceWithOptions(
+				WithTracer(provider.Tracer("test-tracer")),
+				WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+				WithSemConvStability(SemConvStabilityNew),
+			)(tC.handler(cancel)))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+			// a http.TimeoutHandler returns while the handler is still running, the span ends after its metrics are recorded.
+			spans := waitForSpans(recorder, 1)
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			span := spans[0]
+			if span.Status().Code != tC.statusCode {
+				t.Errorf("expected status %v, got %v", tC.statusCode, span.Status().Code)
+			}
+			attrs := span.Attributes()
+			if got := attributeValue(attrs, cancellationKey).AsString(); got != tC.reason {
+				t.Errorf("expected cancellation %q, got %q", tC.reason, got)
+			}
+			if got := attributeValue(attrs, semconv.ErrorTypeKey).AsString(); got != tC.errorType {
+				t.Errorf("expected error.type %q, got %q", tC.errorType, got)
+			}
+
+			var canceledEvents int
+			for _, event := range span.Events() {
+				if event.Name == canceledEvent {
+					canceledEvents++
+				}
+			}
+			var cancellations int64
+			rm := metricdata.ResourceMetrics{}
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatalf("collecting metrics failed due to: %v", err)
+			}
+			for _, m := range rm.ScopeMetrics[0].Metrics {
+				if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == serverRequestCancellationsName {
+					for _, dp := range sum.DataPoints {
+						if v, _ := dp.Attributes.Value(cancellationKey); v == attribute.StringValue(tC.reason) {
+							cancellations += dp.Value
+						}
+					}
+				}
+			}
+			want := 0
+			if tC.reason != "" {
+				want = 1
+			}
+			if canceledEvents != want || cancellations != int64(want) {
+				t.Errorf("expected %d canceled events and cancellations, got %d and %d", want, canceledEvents, cancellations)
+			}
+		})
+	}
+}
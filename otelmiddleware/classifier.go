@@ -0,0 +1,53 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+// StatusClassifier maps the response status code of a request to the status of the span, a description and the error.type.
+// A codes.Error result marks the request as failed in the span, the error count metric and the access log.
+// An empty error.type is recorded as "_OTHER" on the error count metric.
+type StatusClassifier func(statusCode int, r *http.Request) (code codes.Code, description string, errorType string)
+
+// DefaultStatusClassifier marks server errors, a status code of 500 or above, as failed.
+// The description is the status text and the error.type is the status code, for example "503".
+func DefaultStatusClassifier(statusCode int, _ *http.Request) (codes.Code, string, string) {
+	if statusCode >= 500 {
+		return codes.Error, http.StatusText(statusCode), strconv.Itoa(statusCode)
+	}
+	return codes.Unset, "", ""
+}
+
+// classifierKey is the context.Context key under which the StatusClassifier of the middleware is stored.
+type classifierKey struct{}
+
+// withStatusClassifier returns a copy of ctx which carries the StatusClassifier, it is shared with the AccessLog middleware.
+func withStatusClassifier(ctx context.Context, classifier StatusClassifier) context.Context {
+	return context.WithValue(ctx, classifierKey{}, classifier)
+}
+
+// statusClassifierFromContext returns the StatusClassifier stored in ctx, or DefaultStatusClassifier when absent.
+func statusClassifierFromContext(ctx context.Context) StatusClassifier {
+	if classifier, ok := ctx.Value(classifierKey{}).(StatusClassifier); ok {
+		return classifier
+	}
+	return DefaultStatusClassifier
+}
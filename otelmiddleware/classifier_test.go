@@ -0,0 +1,166 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testClassifier treats a 429 as an error and a 503 as expected.
+func testClassifier(statusCode int, r *http.Request) (codes.Code, string, string) {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return codes.Error, "rate limited", "rate_limited"
+	case http.StatusServiceUnavailable:
+		return codes.Unset, "", ""
+	}
+	return DefaultStatusClassifier(statusCode, r)
+}
+
+func TestDefaultStatusClassifier(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if code, description, errorType := DefaultStatusClassifier(http.StatusBadGateway, r); code != codes.Error || description != "Bad Gateway" || errorType != "502" {
+		t.Errorf("unexpected classification of a 502: %v, %q, %q", code, description, errorType)
+	}
+	if code, _, errorType := DefaultStatusClassifier(http.StatusNotFound, r); code != codes.Unset || errorType != "" {
+		t.Errorf("unexpected classification of a 404: %v, %q", code, errorType)
+	}
+}
+
+func TestWithStatusClassifier(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		status      int
+		code        codes.Code
+		description string
+		errorType   string
+	}{
+		{desc: "classified as error", status: http.StatusTooManyRequests, code: codes.Error, description: "rate limited", errorType: "rate_limited"},
+		{desc: "expected server error", status: http.StatusServiceUnavailable, code: codes.Unset},
+		{desc: "default classification", status: http.StatusInternalServerError, code: codes.Error, description: "Internal Server Error", errorType: "500"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			reader := sdkmetric.NewManualReader()
+
+			handler := TraceWithOptions(
+				WithTracer(provider.Tracer("test-tracer")),
+				WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+				WithSemConvStability(SemConvStabilityNew),
+				WithStatusClassifier(testClassifier),
+			)(testHandler(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tC.status)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			span := recorder.Ended()[0]
+			if span.Status().Code != tC.code || span.Status().Description != tC.description {
+				t.Errorf("expected status %v %q, got: %v %q", tC.code, tC.description, span.Status().Code, span.Status().Description)
+			}
+			if got := attributeValue(span.Attributes(), "error.type"); got.AsString() != tC.errorType {
+				t.Errorf("expected error.type %q, got: %q", tC.errorType, got.AsString())
+			}
+
+			rm := metricdata.ResourceMetrics{}
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatalf("collecting metrics failed due to: %v", err)
+			}
+			var failures metricdata.Sum[int64]
+			for _, m := range rm.ScopeMetrics[0].Metrics {
+				if m.Name == serverRequestErrorsName {
+					failures, _ = m.Data.(metricdata.Sum[int64])
+				}
+			}
+			if tC.code != codes.Error {
+				if len(failures.DataPoints) != 0 {
+					t.Errorf("expected no %s data points, got: %v", serverRequestErrorsName, failures.DataPoints)
+				}
+				return
+			}
+			if len(failures.DataPoints) != 1 || failures.DataPoints[0].Value != 1 {
+				t.Fatalf("expected a single %s data point, got: %v", serverRequestErrorsName, failures.DataPoints)
+			}
+			if v, _ := failures.DataPoints[0].Attributes.Value("error.type"); v.AsString() != tC.errorType {
+				t.Errorf("expected error.type %q on the metric, got: %q", tC.errorType, v.AsString())
+			}
+		})
+	}
+}
+
+func TestAccessLogWithStatusClassifier(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		status    int
+		level     slog.Level
+		errorType string
+	}{
+		{desc: "classified as error", status: http.StatusTooManyRequests, level: slog.LevelWarn, errorType: "rate_limited"},
+		{desc: "expected server error", status: http.StatusServiceUnavailable, level: slog.LevelWarn},
+		{desc: "default classification", status: http.StatusInternalServerError, level: slog.LevelError, errorType: "500"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			logger := &testAccessLogger{}
+			handler := TraceWithOptions(WithStatusClassifier(testClassifier))(AccessLog(logger)(
+				testHandler(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(tC.status)
+				})))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if len(logger.entries) != 1 {
+				t.Fatalf("expected 1 access log line, got: %d", len(logger.entries))
+			}
+			entry := logger.entries[0]
+			if entry.level != tC.level {
+				t.Errorf("expected level %v, got: %v", tC.level, entry.level)
+			}
+			if got, _ := entry.attributes.Value("error.type"); got.AsString() != tC.errorType {
+				t.Errorf("expected error.type %q, got: %q", tC.errorType, got.AsString())
+			}
+		})
+	}
+}
+
+func TestAccessLogSamplingWithStatusClassifier(t *testing.T) {
+	logger := &testAccessLogger{}
+	classifier := func(statusCode int, r *http.Request) (codes.Code, string, string) {
+		return codes.Error, "always failing", "test"
+	}
+	handler := TraceWithOptions(WithStatusClassifier(classifier))(AccessLog(logger, WithSuccessSampleRatio(0))(
+		testHandler(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(logger.entries) != 1 {
+		t.Fatalf("expected a request classified as failed to be logged, got: %d lines", len(logger.entries))
+	}
+	if got, _ := logger.entries[0].attributes.Value(attribute.Key("error.type")); got.AsString() != "test" {
+		t.Errorf("expected error.type test, got: %q", got.AsString())
+	}
+}
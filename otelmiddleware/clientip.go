@@ -0,0 +1,140 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+)
+
+// clientAddressKey is the context.Context key under which the resolved client address of a request is stored.
+type clientAddressKey struct{}
+
+// ClientAddressFromRequest returns the client address resolved by TraceWithOptions. When the request did not pass
+// through the middleware, the host of http.Request.RemoteAddr is returned.
+func ClientAddressFromRequest(r *http.Request) string {
+	if address, ok := r.Context().Value(clientAddressKey{}).(string); ok {
+		return address
+	}
+	host, _ := splitHostPort(r.RemoteAddr)
+	return host
+}
+
+// withClientAddress returns a copy of ctx which carries the resolved client address.
+func withClientAddress(ctx context.Context, address string) context.Context {
+	return context.WithValue(ctx, clientAddressKey{}, address)
+}
+
+// parseTrustedProxies parses CIDRs and single IP addresses, invalid entries are passed to the global otel error handler.
+func parseTrustedProxies(proxies []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			otel.Handle(fmt.Errorf("otelmiddleware: invalid trusted proxy %q: %w", proxy, err))
+			continue
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes
+}
+
+// trusted reports whether the address belongs to a trusted proxy.
+func (c *traceConfig) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range c.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddress resolves the address of the client. The Forwarded, X-Forwarded-For and X-Real-IP headers are only honored
+// when the immediate peer is a trusted proxy. The forwarded addresses are walked from right to left, skipping trusted proxies,
+// the first address which is not a trusted proxy is the client. Addresses further to the left can be spoofed by the client.
+func (c *traceConfig) clientAddress(r *http.Request) string {
+	host, _ := splitHostPort(r.RemoteAddr)
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !c.trusted(peer) {
+		return host
+	}
+
+	var chain []string
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		chain = forwardedFor(values)
+	} else if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, value := range values {
+			chain = append(chain, strings.Split(value, ",")...)
+		}
+	} else if value := r.Header.Get("X-Real-IP"); value != "" {
+		chain = []string{value}
+	}
+
+	client := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseForwardedAddr(chain[i])
+		if !ok {
+			// an obfuscated or malformed entry ends the chain, the proxy which appended it is the best known client.
+			break
+		}
+		client = addr.Unmap()
+		if !c.trusted(client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// forwardedFor returns the values of the "for" parameters of RFC 7239 Forwarded headers, in order.
+// An element without a "for" parameter results in an empty entry, so the chain can't be shortened by the client.
+func forwardedFor(values []string) []string {
+	var chain []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			var forValue string
+			for _, pair := range strings.Split(element, ";") {
+				key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					forValue = val
+				}
+			}
+			chain = append(chain, forValue)
+		}
+	}
+	return chain
+}
+
+// parseForwardedAddr parses an address of a forwarding header, such as 192.0.2.60, "192.0.2.60:4711" or "[2001:db8::17]:4711".
+func parseForwardedAddr(value string) (netip.Addr, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if addr, err := netip.ParseAddr(strings.Trim(value, "[]")); err == nil {
+		return addr, true
+	}
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr(), true
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oldsemconv "go.opentelemetry.io/otel/semconv/v1.11.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestClientAddress(t *testing.T) {
+	config := newTraceConfig([]TraceOption{WithTrustedProxies("10.0.0.0/8", "2001:db8::/32", "192.0.2.1")})
+	testCases := []struct {
+		desc       string
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{desc: "untrusted peer ignores headers", remoteAddr: "203.0.113.5:1234", headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, want: "203.0.113.5"},
+		{desc: "trusted peer without headers", remoteAddr: "10.0.0.1:1234", want: "10.0.0.1"},
+		{desc: "x-forwarded-for", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, want: "198.51.100.1"},
+		{desc: "spoofed x-forwarded-for entry", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1, 10.0.0.2"}}, want: "198.51.100.1"},
+		{desc: "multiple x-forwarded-for lines", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4", "198.51.100.1,10.0.0.2"}}, want: "198.51.100.1"},
+		{desc: "trusted single address", remoteAddr: "192.0.2.1:1234", headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, want: "198.51.100.1"},
+		{desc: "all trusted", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, want: "10.0.0.3"},
+		{desc: "malformed entry", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1, garbage, 10.0.0.2"}}, want: "10.0.0.2"},
+		{desc: "forwarded", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"Forwarded": {`for=1.2.3.4, for=198.51.100.1;proto=https, for="10.0.0.2:8080"`}}, want: "198.51.100.1"},
+		{desc: "forwarded ipv6", remoteAddr: "[2001:db8::1]:1234", headers: map[string][]string{"Forwarded": {`For="[2001:db8:cafe::17]:4711"`}}, want: "2001:db8:cafe::17"},
+		{desc: "forwarded obfuscated", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"Forwarded": {"for=198.51.100.1, for=_hidden"}}, want: "10.0.0.1"},
+		{desc: "forwarded unknown", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"Forwarded": {"for=unknown"}}, want: "10.0.0.1"},
+		{desc: "forwarded takes precedence", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"Forwarded": {"for=198.51.100.1"}, "X-Forwarded-For": {"198.51.100.2"}}, want: "198.51.100.1"},
+		{desc: "x-real-ip", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, want: "198.51.100.1"},
+		{desc: "ipv4 mapped ipv6 peer", remoteAddr: "[::ffff:10.0.0.1]:1234", headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, want: "198.51.100.1"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tC.remoteAddr
+			for key, values := range tC.headers {
+				for _, value := range values {
+					r.Header.Add(key, value)
+				}
+			}
+			if got := config.clientAddress(r); got != tC.want {
+				t.Errorf("expected client address %q, got %q", tC.want, got)
+			}
+		})
+	}
+}
+
+func TestClientAddressWithoutTrustedProxies(t *testing.T) {
+	config := newTraceConfig(nil)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := config.clientAddress(r); got != "10.0.0.1" {
+		t.Errorf("expected the peer address, got %q", got)
+	}
+}
+
+func TestWithTrustedProxies(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		stability SemConvStability
+	}{
+		{desc: "new", stability: SemConvStabilityNew},
+		{desc: "old", stability: SemConvStabilityOld},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			var resolved string
+			handler := TraceWithOptions(
+				WithTracer(provider.Tracer("test-tracer")),
+				WithSemConvStability(tC.stability),
+				WithTrustedProxies("10.0.0.0/8"),
+			)(testHandler(func(w http.ResponseWriter, r *http.Request) {
+				resolved = ClientAddressFromRequest(r)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			r.Header.Set("X-Forwarded-For", "1.2.3.4, 198.51.100.1")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if resolved != "198.51.100.1" {
+				t.Errorf("expected ClientAddressFromRequest to return the resolved address, got %q", resolved)
+			}
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			attrs := spans[0].Attributes()
+			if tC.stability == SemConvStabilityNew {
+				if got := attributeValue(attrs, semconv.ClientAddressKey).AsString(); got != "198.51.100.1" {
+					t.Errorf("expected client.address 198.51.100.1, got %q", got)
+				}
+				if got := attributeValue(attrs, semconv.NetworkPeerAddressKey).AsString(); got != "10.0.0.1" {
+					t.Errorf("expected network.peer.address 10.0.0.1, got %q", got)
+				}
+				return
+			}
+			if got := attributeValue(attrs, oldsemconv.HTTPClientIPKey).AsString(); got != "198.51.100.1" {
+				t.Errorf("expected http.client_ip 198.51.100.1, got %q", got)
+			}
+		})
+	}
+}
+
+func TestClientAddressFromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.5:1234"
+	if got := ClientAddressFromRequest(r); got != "203.0.113.5" {
+		t.Errorf("expected the peer address outside of the middleware, got %q", got)
+	}
+}
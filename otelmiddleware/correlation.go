@@ -0,0 +1,62 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// traceResponseHeader is the response header defined by W3C Trace Context Level 2.
+	traceResponseHeader = "traceresponse"
+	// serverTimingHeader is the header used to expose the traceparent to the browser.
+	serverTimingHeader = "Server-Timing"
+)
+
+// correlationHeaders holds the configuration of the trace correlation headers written on a response.
+type correlationHeaders struct {
+	traceResponse bool
+	traceIDHeader string
+	serverTiming  bool
+}
+
+func (c correlationHeaders) enabled() bool {
+	return c.traceResponse || c.traceIDHeader != "" || c.serverTiming
+}
+
+// write adds the configured correlation headers for the span context to the response header.
+func (c correlationHeaders) write(header http.Header, sc trace.SpanContext) {
+	if !sc.IsValid() {
+		return
+	}
+	parent := traceParent(sc)
+	if c.traceResponse {
+		header.Set(traceResponseHeader, parent)
+	}
+	if c.traceIDHeader != "" {
+		header.Set(c.traceIDHeader, sc.TraceID().String())
+	}
+	if c.serverTiming {
+		// Server-Timing can hold multiple metrics, existing entries are kept.
+		header.Add(serverTimingHeader, `traceparent;desc="`+parent+`"`)
+	}
+}
+
+// traceParent formats the span context in the W3C traceparent format, version 00.
+func traceParent(sc trace.SpanContext) string {
+	return "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String()
+}
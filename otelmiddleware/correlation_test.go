@@ -0,0 +1,93 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCorrelationHeaders(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		handler testHandler
+	}{
+		{
+			desc: "WriteHeader",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			},
+		},
+		{
+			desc: "Write",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("hello"))
+			},
+		},
+		{
+			desc:    "implicit",
+			handler: func(http.ResponseWriter, *http.Request) {},
+		},
+		{
+			desc: "Flush",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.(http.Flusher).Flush()
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			handler := TraceWithOptions(
+				WithTracer(provider.Tracer("test-tracer")),
+				WithTraceResponseHeader(),
+				WithTraceIDHeader("X-Trace-Id"),
+				WithServerTimingHeader(),
+			)(testHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Server-Timing", "db;dur=53")
+				tC.handler(w, r)
+				// headers set after the header is written are not sent.
+				w.Header().Set("X-Trace-Id", "overwritten")
+			}))
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ended span, got: %d", len(spans))
+			}
+			sc := spans[0].SpanContext()
+			parent := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+
+			header := res.Result().Header
+			if got := header.Get("traceresponse"); got != parent {
+				t.Errorf("expected traceresponse '%s', got: '%s'", parent, got)
+			}
+			if got := header.Get("X-Trace-Id"); got != sc.TraceID().String() {
+				t.Errorf("expected X-Trace-Id '%s', got: '%s'", sc.TraceID(), got)
+			}
+			timing := header.Values("Server-Timing")
+			if len(timing) != 2 || timing[0] != "db;dur=53" || timing[1] != `traceparent;desc="`+parent+`"` {
+				t.Errorf("expected the traceparent to be added to Server-Timing, got: %v", timing)
+			}
+		})
+	}
+}
@@ -21,21 +21,203 @@ the basic information is extracted using the OpenTelemetry semconv package.
 When a span gets initialized, it uses the following slice of trace.SpanStartOption
 
 	opts := []trace.SpanStartOption{
-		trace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", request)...),
-		trace.WithAttributes(semconv.EndUserAttributesFromHTTPRequest(request)...),
-		trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(request.Host, extractRoute(request.RequestURI), request)...),
+		trace.WithAttributes(config.semconv.serverRequestAttributes(request, route, client)...),
+		trace.WithAttributes(semconv.TelemetrySDKLanguageGo),
 		trace.WithSpanKind(trace.SpanKindServer),
 	}
 
+The emitted attributes depend on the selected SemConvStability. SemConvStabilityOld emits the v1.11.0 attributes such as
+http.method and net.peer.ip, SemConvStabilityNew emits the stable attributes such as http.request.method, url.path,
+server.address, client.address, http.response.status_code and error.type. SemConvStabilityDuplicate emits both, which
+allows dashboards to migrate gradually. The mode is resolved from the OTEL_SEMCONV_STABILITY_OPT_IN environment variable
+("http" or "http/dup") and can be set using the WithSemConvStability TraceOption function.
+
 The slice can be extended using the WithAttributes TraceOption function.
 
+The span is named after the method and route template, for example "GET /users/{id}". The route is read from
+http.Request.Pattern as set by a http.ServeMux, or from a route tagged using TagRoute. When the route is unknown
+only the method is used. A method which is not known to the semantic conventions is named HTTP, this keeps the number of
+span names bounded. The name can be customized using the
+WithSpanNameFormatter TraceOption function.
+
+Requests such as health checks and probes can be excluded from tracing using the WithFilter TraceOption function.
+The package provides PathFilter, PathPrefixFilter, PathGlobFilter, MethodFilter and UserAgentFilter. A filtered request
+is served without a span and without metrics, the incoming span context is still extracted so downstream code keeps the parent.
+
+A panic raised by the next http.Handler is recorded on the span as an exception event including the stack trace
+when the WithPanicRecording or WithPanicRecovery TraceOption function is used. WithPanicRecording raises the panic again,
+WithPanicRecovery answers the request with a configurable response, a 500 Internal Server Error by default. When the handler
+already wrote the header the response can not be replaced, it is aborted by raising http.ErrAbortHandler so the client sees
+the failure. A panic with http.ErrAbortHandler is always passed on to the http.Server.
+
+Request and response headers are recorded as http.request.header.<name> and http.response.header.<name> span attributes
+using the WithRequestHeaders and WithResponseHeaders TraceOption functions, "*" records all headers. The values of
+Authorization, Proxy-Authorization, Cookie, Set-Cookie and the headers passed to WithRedactedHeaders are always redacted.
+
+For debugging payload issues, request and response bodies are recorded as http.request.body and http.response.body
+span events using the WithBodyCapture TraceOption function. The BodyCaptureOption functions WithBodyMaxBytes,
+WithBodyContentTypes, WithBodySampleRatio and WithBodyRedactor configure the capture. The request body is replayed,
+so the handler can still read it in full. The response body is captured using WrapResponseWriter.Tee.
+
+Internet-facing services should not trust the span context sent by clients. With the WithPublicEndpoint or
+WithPublicEndpointFn TraceOption function every (matching) request starts a new root span, the extracted remote span
+context is recorded as a span link instead of a parent. WithPublicBaggageDropped also removes the incoming baggage.
+
+To tie a bug report to a trace, the trace context can be written on the response using the WithTraceResponseHeader,
+WithTraceIDHeader and WithServerTimingHeader TraceOption functions. The headers are written right before the header of
+the response is written or flushed, so they are not lost once the handler starts writing.
+
+Handlers can log with the trace context of the request when the WithLoggerFactory TraceOption function is used. The
+LoggerFactory places a logger in the request context, it receives the span and the method and route attributes.
+The ContextWithTracing methods of the otelslog, otelzerolog and otellogrus loggers can be used as a LoggerFactory.
+
+AccessLog returns a middleware which writes one access log line per request with the method, route, status code, bytes written,
+duration, client address and the trace and span ID. It writes through an AccessLogger, which is implemented by the otelslog,
+otelzerolog and otellogrus loggers. Placed after TraceWithOptions, the line correlates with the span of the request.
+The level is selected by status class using WithAccessLogLevel and successful requests can be sampled using WithSuccessSampleRatio.
+
+When the handler hijacks the connection, for example to upgrade it to a WebSocket, the span records an http.connection.upgraded
+event and the response of an upgrade request is reported as 101 Switching Protocols, the status code of any other hijacked
+connection is left unset. The span ends once the handler returns, with the WithUpgradedConnectionSpan TraceOption function
+a child span tracks the hijacked net.Conn until it is closed, including the bytes read and written on the connection.
+
+For server-sent events and chunked streams the span duration says little about the experienced latency. The WrapResponseWriter
+records the time to first byte, the number of flushes and the time of the last flush. The span carries the
+http.server.time_to_first_byte attribute, a flushed response also the http.response.flush_count attribute and the
+http.response.first_byte and http.response.last_flush events.
+
+By default a response with a status code of 500 or above is an error. The WithStatusClassifier TraceOption function
+replaces the DefaultStatusClassifier, a StatusClassifier maps the status code and request to a codes.Code, a description
+and an error.type. This allows treating a 429 as an error or a 503 during a drain as expected. The classification drives
+the span status, the error.type attribute, the http.server.request.errors metric and the level of the AccessLog.
+
+Behind a load balancer http.Request.RemoteAddr is the address of the load balancer. The WithTrustedProxies TraceOption function
+takes the CIDRs of the trusted proxies, when the immediate peer is a trusted proxy the client.address is resolved from the
+Forwarded, X-Forwarded-For or X-Real-IP header. The forwarded addresses are read from right to left and the first address
+which is not a trusted proxy is the client, addresses added by the client itself are ignored. The network.peer.address keeps
+the address of the peer. Handlers read the resolved address using ClientAddressFromRequest.
+
+Clients which send an X-Request-ID are supported using the WithRequestID TraceOption function. The incoming request ID
+is accepted, or a UUIDv7 is generated, WithRequestIDGenerator(TraceIDRequestID) uses the trace ID instead. The request ID
+is recorded as the http.request.id span attribute, added to the baggage as request.id, stored in the request context and
+echoed on the response. Handlers read it using RequestIDFromContext, the otelslog, otelzerolog and otellogrus loggers add
+it to their logs as the requestID field.
+
+Routes are configured individually using the WithRouteOverride TraceOption function. The pattern uses the syntax and matching
+rules of http.ServeMux. WithRouteAttributes adds attributes to the spans of the route, WithRouteTracingDisabled serves the
+route without a span and WithRouteSamplingPriority passes a sampling.priority to the sampler as the SamplingPriorityKey span
+start attribute. A sampler honoring it samples a span with a priority above 0 and drops a span with a priority of 0.
+The middleware only depends on the OpenTelemetry API, the sampler is provided by the
+github.com/vincentfree/opentelemetry/otelmiddleware/prioritysampler module as it depends on the SDK.
+
+The request body is wrapped to count the bytes read and the time spent reading, which are recorded as the http.request.body.size
+and http.request.body.read_duration span attributes. The first read error, such as a client abort or a http.MaxBytesError,
+is recorded as an http.request.body.read_error span event. The error of a http.MaxBytesReader is seen both when it is placed
+before the middleware and when the handler sets it as the body of the request it received, a limit applied to a copy of the
+request is not seen. When the Content-Length is unknown, for example for chunked uploads, the bytes read are recorded in the http.server.request.body.size metric.
+
+While the request is served, the middleware watches the request context. A request canceled because the client went away
+records error.type 499, a request canceled by a deadline, for example of a http.TimeoutHandler placed before the middleware,
+records error.type timeout. Both set the http.request.cancellation attribute to client or timeout, add an http.request.canceled
+event at the moment of the cancellation and are counted in the http.server.request.cancellations metric.
+
+Tail latency is found using the WithSlowRequestThreshold TraceOption function. A request taking longer than the threshold
+records the slow_request span attribute and event. When an AccessLogger, such as the otelslog, otelzerolog or otellogrus
+logger, is passed a warning including the trace ID is written for every slow request. The threshold of a route is set
+using the WithRouteSlowRequestThreshold RouteOption function.
+
+The end user of a request is recorded using the WithIdentityExtractor TraceOption function. An IdentityExtractor returns
+the Identity of a request, which is recorded as the enduser.id, enduser.role, enduser.scope and enduser.tenant span attributes.
+UnverifiedJWTIdentity reads the sub, tenant, scope and role claims of the bearer token or of a session cookie. The signature
+of the token is not verified, so only use it for telemetry and never for authorization. The WithIdentityBaggage TraceOption
+function also adds the attributes to the baggage, which is sent to downstream services.
+
 After these options are applied, a new span is created and the middleware will pass the http.ResponseWriter and http.Request to the next http.Handler.
 
+Next to the trace.Span, the middleware records the HTTP server metrics http.server.request.duration, http.server.active_requests,
+http.server.request.body.size, http.server.response.body.size, http.server.time_to_first_byte, http.server.request.errors
+and http.server.request.cancellations. The metrics carry the same route and method attributes as the span.
+The metric.MeterProvider defaults to otel.GetMeterProvider and can be replaced using the WithMeterProvider TraceOption function.
+
+Outgoing requests are traced using a Transport, a http.RoundTripper which starts a trace.SpanKindClient span for every request
+and injects the span context into a copy of the request, the request passed to it is never modified. The span is named after
+the method, a method which is not known to the semantic conventions is named HTTP. NewClient returns a http.Client using the Transport.
+Both accept the same TraceOption functions as TraceWithOptions.
+
+A httputil.ReverseProxy is instrumented using WrapReverseProxy. The returned proxy sends the upstream requests using a Transport,
+so the upstream hop becomes a client span under the server span and the span context is injected into the proxied request.
+The client span records the upstream address, as network.peer.address, and the upstream status code. Errors passed to the
+ErrorHandler are recorded as an http.proxy.error event on the server span.
+
+Connection churn, keep-alive reuse and TLS handshake failures are not visible in request spans. InstrumentServer installs
+ConnState and ConnContext hooks on a http.Server and records the http.server.open_connections metric by http.connection.state,
+the http.server.connection.duration metric including the network.protocol.version, tls.protocol.version and tls.cipher of
+TLS connections, and the http.server.tls.handshake.errors metric. Request spans record the network.protocol.version, in every
+SemConvStability mode, the tls.* attributes of TLS requests and, on an instrumented server, http.connection.reused.
+Handshake errors are recorded using a GetConfigForClient callback on the TLSConfig of the server, they count the
+connections which sent a ClientHello but did not complete the handshake.
+The server needs a TLSConfig when InstrumentServer is called, an empty tls.Config will do.
+
 Functions
 
 	func TraceWithOptions(opt ...TraceOption) func(next http.Handler) http.Handler
 	func Trace(next http.Handler) http.Handler
+	func NewTransport(base http.RoundTripper, opt ...TraceOption) *Transport
+	func NewClient(opt ...TraceOption) *http.Client
+	func WrapReverseProxy(proxy *httputil.ReverseProxy, opt ...TraceOption) *httputil.ReverseProxy
+	func InstrumentServer(srv *http.Server, opt ...TraceOption)
 	func WithAttributes(attributes ...attribute.KeyValue) TraceOption
+	func WithMeterProvider(provider metric.MeterProvider) TraceOption
+	func WithSpanNameFormatter(formatter func(*http.Request) string) TraceOption
+	func WithSemConvStability(stability SemConvStability) TraceOption
+	func WithFilter(filters ...Filter) TraceOption
+	func WithPanicRecording() TraceOption
+	func WithPanicRecovery(response http.Handler) TraceOption
+	func WithRequestHeaders(headers ...string) TraceOption
+	func WithResponseHeaders(headers ...string) TraceOption
+	func WithRedactedHeaders(headers ...string) TraceOption
+	func WithBodyCapture(opts ...BodyCaptureOption) TraceOption
+	func WithBodyMaxBytes(maxBytes int) BodyCaptureOption
+	func WithBodyContentTypes(contentTypes ...string) BodyCaptureOption
+	func WithBodySampleRatio(ratio float64) BodyCaptureOption
+	func WithBodyRedactor(redactor func(contentType string, body []byte) []byte) BodyCaptureOption
+	func WithPublicEndpoint() TraceOption
+	func WithPublicEndpointFn(fn func(*http.Request) bool) TraceOption
+	func WithPublicBaggageDropped() TraceOption
+	func WithTraceResponseHeader() TraceOption
+	func WithTraceIDHeader(header string) TraceOption
+	func WithServerTimingHeader() TraceOption
+	func WithLoggerFactory(factory LoggerFactory) TraceOption
+	func WithUpgradedConnectionSpan() TraceOption
+	func WithStatusClassifier(classifier StatusClassifier) TraceOption
+	func DefaultStatusClassifier(statusCode int, _ *http.Request) (codes.Code, string, string)
+	func WithTrustedProxies(proxies ...string) TraceOption
+	func ClientAddressFromRequest(r *http.Request) string
+	func WithRequestID(opts ...RequestIDOption) TraceOption
+	func WithRequestIDHeader(header string) RequestIDOption
+	func WithRequestIDGenerator(generator RequestIDGenerator) RequestIDOption
+	func UUIDv7RequestID(_ trace.SpanContext) string
+	func TraceIDRequestID(sc trace.SpanContext) string
+	func RequestIDFromContext(ctx context.Context) string
+	func WithRouteOverride(pattern string, opts ...RouteOption) TraceOption
+	func WithRouteAttributes(attributes ...attribute.KeyValue) RouteOption
+	func WithRouteSamplingPriority(priority int) RouteOption
+	func WithRouteTracingDisabled() RouteOption
+	func WithRouteSlowRequestThreshold(threshold time.Duration) RouteOption
+	func WithSlowRequestThreshold(threshold time.Duration, logger AccessLogger) TraceOption
+	func WithIdentityExtractor(extractor IdentityExtractor) TraceOption
+	func WithIdentityBaggage() TraceOption
+	func UnverifiedJWTIdentity(cookies ...string) IdentityExtractor
+	func AccessLog(logger AccessLogger, opt ...AccessLogOption) func(next http.Handler) http.Handler
+	func WithAccessLogLevel(statusClass int, level slog.Level) AccessLogOption
+	func WithSuccessSampleRatio(ratio float64) AccessLogOption
+	func PathFilter(paths ...string) Filter
+	func PathPrefixFilter(prefixes ...string) Filter
+	func PathGlobFilter(patterns ...string) Filter
+	func MethodFilter(methods ...string) Filter
+	func UserAgentFilter(userAgents ...string) Filter
+	func TagRoute(route string, next http.Handler) http.Handler
+	func RouteFromRequest(r *http.Request) string
 	func WithPropagator(p propagation.TextMapPropagator) TraceOption
 	func WithServiceName(serviceName string) TraceOption
 	func WithTracer(tracer trace.Tracer) TraceOption
@@ -43,6 +225,19 @@ Functions
 Types
 
 	type TraceOption func(*traceConfig)
+	type Transport struct
+	type SemConvStability int
+	type Filter func(*http.Request) bool
+	type BodyCaptureOption func(*bodyCaptureConfig)
+	type LoggerFactory func(ctx context.Context, span trace.Span, attributes []attribute.KeyValue) context.Context
+	type AccessLogger interface
+	type AccessLogOption func(*accessLogConfig)
+	type StatusClassifier func(statusCode int, r *http.Request) (code codes.Code, description string, errorType string)
+	type RequestIDOption func(*requestIDConfig)
+	type RequestIDGenerator func(sc trace.SpanContext) string
+	type RouteOption func(*routeConfig)
+	type Identity struct
+	type IdentityExtractor func(r *http.Request) (Identity, bool)
 
 Structs
 
@@ -51,6 +246,29 @@ Structs
 		propagator propagation.TextMapPropagator
 		attributes []attribute.KeyValue
 		serviceName string
+		meterProvider metric.MeterProvider
+		spanNameFormatter func(*http.Request) string
+		semconv SemConvStability
+		filters []Filter
+		recordPanics bool
+		panicResponse http.Handler
+		requestHeaders []string
+		responseHeaders []string
+		redactedHeaders []string
+		bodyCapture *bodyCaptureConfig
+		publicEndpointFn func(*http.Request) bool
+		dropPublicBaggage bool
+		correlation correlationHeaders
+		loggerFactory LoggerFactory
+		connectionSpan bool
+		statusClassifier StatusClassifier
+		trustedProxies []netip.Prefix
+		requestID *requestIDConfig
+		routes *routeOverrides
+		slowThreshold time.Duration
+		slowLogger AccessLogger
+		identityExtractor IdentityExtractor
+		identityBaggage bool
 	}
 */
 package otelmiddleware // import "github.com/vincentfree/opentelemetry/otelmiddleware"
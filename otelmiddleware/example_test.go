@@ -15,10 +15,18 @@
 package otelmiddleware_test
 
 import (
+	"context"
+	"crypto/tls"
 	"github.com/vincentfree/opentelemetry/otelmiddleware"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
 )
 
 type exampleHandler func(http.ResponseWriter, *http.Request)
@@ -54,6 +62,13 @@ func ExampleWithAttributes() {
 	http.Handle("/", handler(eh))
 }
 
+func ExampleWithMeterProvider() {
+	// returns a function that excepts a http.Handler.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithMeterProvider(otel.GetMeterProvider()))
+	// pass a http.Handler to extend it with Tracing and metric functionality.
+	http.Handle("/", handler(eh))
+}
+
 func ExampleWithPropagator() {
 	// returns a function that excepts a http.Handler.
 	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithPropagator(otel.GetTextMapPropagator()))
@@ -71,3 +86,221 @@ func ExampleTraceWithOptions() {
 func ExampleTrace() {
 	http.Handle("/", otelmiddleware.Trace(eh))
 }
+
+func ExampleNewClient() {
+	// returns a http.Client which creates a client span for every outgoing request.
+	client := otelmiddleware.NewClient(otelmiddleware.WithPropagator(otel.GetTextMapPropagator()))
+	_, _ = client.Get("http://localhost:8080/")
+}
+
+func ExampleNewTransport() {
+	// wrap an existing http.RoundTripper with tracing functionality.
+	client := &http.Client{Transport: otelmiddleware.NewTransport(http.DefaultTransport)}
+	_, _ = client.Get("http://localhost:8080/")
+}
+
+func ExampleWithSpanNameFormatter() {
+	// returns a function that excepts a http.Handler.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithSpanNameFormatter(func(r *http.Request) string {
+		return "HTTP " + r.Method + " " + otelmiddleware.RouteFromRequest(r)
+	}))
+	mux := http.NewServeMux()
+	mux.Handle("GET /users/{id}", eh)
+	// the span name is set after the http.ServeMux matched the route, "HTTP GET /users/{id}".
+	http.Handle("/", handler(mux))
+}
+
+func ExampleTagRoute() {
+	// tag the handler with its route template for routers that don't set http.Request.Pattern.
+	http.Handle("/users/", otelmiddleware.Trace(otelmiddleware.TagRoute("/users/{id}", eh)))
+}
+
+func ExampleWithSemConvStability() {
+	// emit both the old and the stable HTTP semantic conventions while dashboards are migrated.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithSemConvStability(otelmiddleware.SemConvStabilityDuplicate))
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithFilter() {
+	// health checks, metric scrapes and kubernetes probes are not traced.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithFilter(
+		otelmiddleware.PathFilter("/healthz", "/metrics"),
+		otelmiddleware.UserAgentFilter("kube-probe/"),
+	))
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithPanicRecovery() {
+	// a panic is recorded on the span and answered with a 500 Internal Server Error.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithPanicRecovery(nil))
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithRequestHeaders() {
+	// record the tenant and content type headers, the api key is never recorded.
+	handler := otelmiddleware.TraceWithOptions(
+		otelmiddleware.WithRequestHeaders("X-Tenant-ID", "X-Api-Key"),
+		otelmiddleware.WithResponseHeaders("Content-Type"),
+		otelmiddleware.WithRedactedHeaders("X-Api-Key"),
+	)
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithBodyCapture() {
+	// capture up to 1KiB of the JSON bodies of 1% of the requests.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithBodyCapture(
+		otelmiddleware.WithBodyMaxBytes(1024),
+		otelmiddleware.WithBodyContentTypes("application/json"),
+		otelmiddleware.WithBodySampleRatio(0.01),
+	))
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithPublicEndpoint() {
+	// the span context sent by clients is linked to a new root span, the incoming baggage is dropped.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithPublicEndpoint(), otelmiddleware.WithPublicBaggageDropped())
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithTraceIDHeader() {
+	// write the trace ID and the W3C traceresponse header on every response.
+	handler := otelmiddleware.TraceWithOptions(
+		otelmiddleware.WithTraceIDHeader("X-Trace-Id"),
+		otelmiddleware.WithTraceResponseHeader(),
+		otelmiddleware.WithServerTimingHeader(),
+	)
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithLoggerFactory() {
+	type loggerKey struct{}
+	// place a logger carrying the trace context in the request context, the ContextWithTracing methods
+	// of the otelslog, otelzerolog and otellogrus loggers can be passed as well.
+	factory := func(ctx context.Context, span trace.Span, attributes []attribute.KeyValue) context.Context {
+		logger := slog.Default().With("traceID", span.SpanContext().TraceID().String())
+		return context.WithValue(ctx, loggerKey{}, logger)
+	}
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithLoggerFactory(factory))
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+type exampleAccessLogger struct{}
+
+func (exampleAccessLogger) LogAccess(ctx context.Context, level slog.Level, msg string, span trace.Span, attributes []attribute.KeyValue) {
+	slog.Default().Log(ctx, level, msg, "traceID", span.SpanContext().TraceID().String(), "attributes", attributes)
+}
+
+func ExampleAccessLog() {
+	// log 10% of the successful requests and every failed request, the otelslog, otelzerolog and otellogrus
+	// loggers implement the AccessLogger interface as well.
+	accessLog := otelmiddleware.AccessLog(exampleAccessLogger{}, otelmiddleware.WithSuccessSampleRatio(0.1))
+	// place the access log after the tracing middleware so the log correlates with the span.
+	http.Handle("/", otelmiddleware.Trace(accessLog(eh)))
+}
+
+func ExampleWithUpgradedConnectionSpan() {
+	// track WebSocket connections until they are closed using a child span of the upgrade request.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithUpgradedConnectionSpan())
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/ws", handler(eh))
+}
+
+func ExampleWithStatusClassifier() {
+	// treat rate limited requests as an error and a 503 during a drain as expected.
+	classifier := func(statusCode int, r *http.Request) (codes.Code, string, string) {
+		switch statusCode {
+		case http.StatusTooManyRequests:
+			return codes.Error, "rate limited", "rate_limited"
+		case http.StatusServiceUnavailable:
+			return codes.Unset, "", ""
+		}
+		return otelmiddleware.DefaultStatusClassifier(statusCode, r)
+	}
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithStatusClassifier(classifier))
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithTrustedProxies() {
+	// honor the forwarding headers set by the load balancers in the private network.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithTrustedProxies("10.0.0.0/8", "fd00::/8"))
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello " + otelmiddleware.ClientAddressFromRequest(r)))
+	})))
+}
+
+func ExampleWithRequestID() {
+	// accept the X-Request-ID of legacy clients, requests without one get the trace ID as request ID.
+	handler := otelmiddleware.TraceWithOptions(otelmiddleware.WithRequestID(
+		otelmiddleware.WithRequestIDGenerator(otelmiddleware.TraceIDRequestID),
+	))
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("request " + otelmiddleware.RequestIDFromContext(r.Context())))
+	})))
+}
+
+func ExampleWithRouteOverride() {
+	// sample every checkout, never trace the internal endpoints and tag the user routes with the owning team.
+	handler := otelmiddleware.TraceWithOptions(
+		otelmiddleware.WithRouteOverride("POST /checkout", otelmiddleware.WithRouteSamplingPriority(1)),
+		otelmiddleware.WithRouteOverride("/internal/", otelmiddleware.WithRouteTracingDisabled()),
+		otelmiddleware.WithRouteOverride("/users/{id}", otelmiddleware.WithRouteAttributes(attribute.String("team", "accounts"))),
+	)
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithRouteSamplingPriority() {
+	// always sample the checkout, the sampler of the TracerProvider has to honor the priority,
+	// for example the sampler of the github.com/vincentfree/opentelemetry/otelmiddleware/prioritysampler module.
+	handler := otelmiddleware.TraceWithOptions(
+		otelmiddleware.WithRouteOverride("POST /checkout", otelmiddleware.WithRouteSamplingPriority(1)),
+	)
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWrapReverseProxy() {
+	upstream, _ := url.Parse("http://localhost:9090")
+	// the upstream hop is traced as a client span under the server span.
+	proxy := otelmiddleware.WrapReverseProxy(httputil.NewSingleHostReverseProxy(upstream))
+	http.Handle("/", otelmiddleware.Trace(proxy))
+}
+
+func ExampleInstrumentServer() {
+	// the TLSConfig is required to record the TLS handshake errors.
+	srv := &http.Server{Addr: ":8443", Handler: otelmiddleware.Trace(eh), TLSConfig: &tls.Config{}}
+	// record the connection and TLS metrics of the server, before it starts.
+	otelmiddleware.InstrumentServer(srv)
+	_ = srv.ListenAndServeTLS("cert.pem", "key.pem")
+}
+
+func ExampleWithSlowRequestThreshold() {
+	// warn about requests taking longer than 500ms, reports may take up to 5s.
+	handler := otelmiddleware.TraceWithOptions(
+		otelmiddleware.WithSlowRequestThreshold(500*time.Millisecond, exampleAccessLogger{}),
+		otelmiddleware.WithRouteOverride("/reports/", otelmiddleware.WithRouteSlowRequestThreshold(5*time.Second)),
+	)
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
+
+func ExampleWithIdentityExtractor() {
+	// record the end user of the bearer token or session cookie, and propagate it to downstream services.
+	handler := otelmiddleware.TraceWithOptions(
+		otelmiddleware.WithIdentityExtractor(otelmiddleware.UnverifiedJWTIdentity("session")),
+		otelmiddleware.WithIdentityBaggage(),
+	)
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(eh))
+}
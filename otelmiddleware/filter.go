@@ -0,0 +1,100 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Filter is a predicate over a http.Request, it returns true when the request should not be traced.
+// Filters can be passed to the middleware using the WithFilter TraceOption function.
+type Filter func(*http.Request) bool
+
+// PathFilter returns a Filter that matches requests with one of the given paths exactly, for example "/healthz".
+func PathFilter(paths ...string) Filter {
+	set := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		set[p] = struct{}{}
+	}
+	return func(r *http.Request) bool {
+		_, ok := set[r.URL.Path]
+		return ok
+	}
+}
+
+// PathPrefixFilter returns a Filter that matches requests of which the path starts with one of the given prefixes, for example "/debug/".
+func PathPrefixFilter(prefixes ...string) Filter {
+	return func(r *http.Request) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathGlobFilter returns a Filter that matches requests of which the path matches one of the given glob patterns, for example "/probes/*".
+// The patterns use the syntax of path.Match, a malformed pattern never matches.
+func PathGlobFilter(patterns ...string) Filter {
+	return func(r *http.Request) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, r.URL.Path); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MethodFilter returns a Filter that matches requests with one of the given methods, for example http.MethodOptions.
+func MethodFilter(methods ...string) Filter {
+	return func(r *http.Request) bool {
+		for _, method := range methods {
+			if strings.EqualFold(r.Method, method) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// UserAgentFilter returns a Filter that matches requests of which the User-Agent header contains one of the given values, for example "kube-probe/".
+func UserAgentFilter(userAgents ...string) Filter {
+	return func(r *http.Request) bool {
+		ua := r.UserAgent()
+		if ua == "" {
+			return false
+		}
+		for _, userAgent := range userAgents {
+			if strings.Contains(ua, userAgent) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// filtered reports whether one of the filters matches the request.
+func filtered(filters []Filter, r *http.Request) bool {
+	for _, f := range filters {
+		if f(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestFilters(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		filter    Filter
+		method    string
+		target    string
+		userAgent string
+		want      bool
+	}{
+		{desc: "exact path", filter: PathFilter("/healthz"), target: "/healthz", want: true},
+		{desc: "exact path mismatch", filter: PathFilter("/healthz"), target: "/healthz/deep"},
+		{desc: "path prefix", filter: PathPrefixFilter("/debug/"), target: "/debug/pprof", want: true},
+		{desc: "path prefix mismatch", filter: PathPrefixFilter("/debug/"), target: "/users"},
+		{desc: "path glob", filter: PathGlobFilter("/probes/*"), target: "/probes/ready", want: true},
+		{desc: "path glob mismatch", filter: PathGlobFilter("/probes/*"), target: "/probes/ready/deep"},
+		{desc: "method", filter: MethodFilter(http.MethodOptions), method: http.MethodOptions, target: "/", want: true},
+		{desc: "method mismatch", filter: MethodFilter(http.MethodOptions), target: "/"},
+		{desc: "user agent", filter: UserAgentFilter("kube-probe/"), target: "/", userAgent: "kube-probe/1.29", want: true},
+		{desc: "user agent mismatch", filter: UserAgentFilter("kube-probe/"), target: "/", userAgent: "curl/8.0"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			method := tC.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, tC.target, nil)
+			if tC.userAgent != "" {
+				r.Header.Set("User-Agent", tC.userAgent)
+			}
+			if got := tC.filter(r); got != tC.want {
+				t.Errorf("filter should return %t, but was: %t", tC.want, got)
+			}
+		})
+	}
+}
+
+func TestWithFilter(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	var parent trace.SpanContext
+	handler := TraceWithOptions(
+		WithTracer(provider.Tracer("test-tracer")),
+		WithPropagator(propagation.TraceContext{}),
+		WithFilter(PathFilter("/healthz")),
+	)(testHandler(func(w http.ResponseWriter, r *http.Request) {
+		parent = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(recorder.Ended()) != 0 {
+		t.Errorf("expected no spans for a filtered request, got: %d", len(recorder.Ended()))
+	}
+	if parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || !parent.IsRemote() {
+		t.Errorf("expected the incoming parent to be extracted, got: %v", parent)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	if len(recorder.Ended()) != 1 {
+		t.Errorf("expected a span for an unfiltered request, got: %d", len(recorder.Ended()))
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// requestHeaderPrefix and responseHeaderPrefix are the attribute key prefixes for captured headers.
+	requestHeaderPrefix  = "http.request.header."
+	responseHeaderPrefix = "http.response.header."
+	// redactedValue replaces the value of a redacted header.
+	redactedValue = "[REDACTED]"
+	// wildcardHeader captures all headers.
+	wildcardHeader = "*"
+)
+
+// defaultRedactedHeaders are always redacted, they contain credentials.
+var defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// headerCapture holds an allow-list of headers which are recorded as span attributes.
+type headerCapture struct {
+	all   bool
+	names []string
+}
+
+// newHeaderCapture creates a headerCapture for the given header names, "*" captures all headers.
+func newHeaderCapture(names []string) headerCapture {
+	capture := headerCapture{}
+	for _, name := range names {
+		if name == wildcardHeader {
+			capture.all = true
+			continue
+		}
+		capture.names = append(capture.names, http.CanonicalHeaderKey(name))
+	}
+	return capture
+}
+
+func (h headerCapture) enabled() bool {
+	return h.all || len(h.names) > 0
+}
+
+// attributes returns the captured headers as attributes, the key is the prefix followed by the lower case header name.
+// The values of redacted headers are replaced.
+func (h headerCapture) attributes(prefix string, header http.Header, redacted map[string]struct{}) []attribute.KeyValue {
+	names := h.names
+	if h.all {
+		names = make([]string, 0, len(header))
+		for name := range header {
+			names = append(names, name)
+		}
+		// sort the names to keep the order of the attributes stable.
+		slices.Sort(names)
+	}
+
+	var attributes []attribute.KeyValue
+	for _, name := range names {
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if _, ok := redacted[http.CanonicalHeaderKey(name)]; ok {
+			values = []string{redactedValue}
+		}
+		attributes = append(attributes, attribute.StringSlice(prefix+strings.ToLower(name), values))
+	}
+	return attributes
+}
+
+// redactedHeaderSet returns a set of canonical header names which contains the default and the given headers.
+func redactedHeaderSet(headers []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(defaultRedactedHeaders)+len(headers))
+	for _, name := range append(slices.Clone(defaultRedactedHeaders), headers...) {
+		set[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHeaderCapture(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		options []TraceOption
+		want    map[attribute.Key][]string
+		absent  []attribute.Key
+	}{
+		{
+			desc:    "allow-listed headers",
+			options: []TraceOption{WithRequestHeaders("x-tenant-id"), WithResponseHeaders("Content-Type")},
+			want: map[attribute.Key][]string{
+				"http.request.header.x-tenant-id":   {"tenant-a"},
+				"http.response.header.content-type": {"application/json"},
+			},
+			absent: []attribute.Key{"http.request.header.authorization", "http.request.header.x-forwarded-for"},
+		},
+		{
+			desc:    "wildcard redacts credentials",
+			options: []TraceOption{WithRequestHeaders("*"), WithResponseHeaders("*")},
+			want: map[attribute.Key][]string{
+				"http.request.header.x-tenant-id":     {"tenant-a"},
+				"http.request.header.x-forwarded-for": {"10.0.0.1", "10.0.0.2"},
+				"http.request.header.authorization":   {redactedValue},
+				"http.request.header.cookie":          {redactedValue},
+				"http.response.header.set-cookie":     {redactedValue},
+			},
+		},
+		{
+			desc:    "custom redacted header",
+			options: []TraceOption{WithRequestHeaders("X-Tenant-ID"), WithRedactedHeaders("x-tenant-id")},
+			want: map[attribute.Key][]string{
+				"http.request.header.x-tenant-id": {redactedValue},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			options := append([]TraceOption{WithTracer(provider.Tracer("test-tracer"))}, tC.options...)
+
+			handler := TraceWithOptions(options...)(testHandler(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Set-Cookie", "session=secret")
+				w.WriteHeader(http.StatusOK)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Tenant-ID", "tenant-a")
+			r.Header.Set("Authorization", "Bearer secret")
+			r.Header.Set("Cookie", "session=secret")
+			r.Header.Add("X-Forwarded-For", "10.0.0.1")
+			r.Header.Add("X-Forwarded-For", "10.0.0.2")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ended span, got: %d", len(spans))
+			}
+			got := map[attribute.Key][]string{}
+			for _, attr := range spans[0].Attributes() {
+				got[attr.Key] = attr.Value.AsStringSlice()
+			}
+			for key, want := range tC.want {
+				if !slices.Equal(got[key], want) {
+					t.Errorf("expected attribute %s to be %v, got: %v", key, want, got[key])
+				}
+			}
+			for _, key := range tC.absent {
+				if _, ok := got[key]; ok {
+					t.Errorf("expected attribute %s to be absent", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	// enduserTenantKey is not part of the semantic conventions, it records the tenant of the end user.
+	enduserTenantKey = attribute.Key("enduser.tenant")
+	// maxTokenLength is the maximum length of a token read by UnverifiedJWTIdentity, larger tokens are ignored.
+	maxTokenLength = 8 << 10
+)
+
+// Identity describes the end user of a request.
+type Identity struct {
+	// ID is recorded as enduser.id, for example the subject of a token.
+	ID string
+	// Role is recorded as enduser.role.
+	Role string
+	// Scope is recorded as enduser.scope, for example the space separated scopes of a token.
+	Scope string
+	// Tenant is recorded as enduser.tenant.
+	Tenant string
+}
+
+// IdentityExtractor returns the Identity of the end user of a request, it returns false when the end user is unknown.
+// It can be passed to the middleware using the WithIdentityExtractor TraceOption function.
+type IdentityExtractor func(r *http.Request) (Identity, bool)
+
+// attributes returns the enduser attributes of the Identity, empty fields are left out.
+func (i Identity) attributes() []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	if i.ID != "" {
+		attributes = append(attributes, semconv.EnduserID(i.ID))
+	}
+	if i.Role != "" {
+		attributes = append(attributes, semconv.EnduserRole(i.Role))
+	}
+	if i.Scope != "" {
+		attributes = append(attributes, semconv.EnduserScope(i.Scope))
+	}
+	if i.Tenant != "" {
+		attributes = append(attributes, enduserTenantKey.String(i.Tenant))
+	}
+	return attributes
+}
+
+// withBaggage returns a copy of ctx of which the baggage carries the enduser attributes of the Identity.
+func (i Identity) withBaggage(ctx context.Context) context.Context {
+	for _, attr := range i.attributes() {
+		ctx = withBaggageMember(ctx, string(attr.Key), attr.Value.AsString())
+	}
+	return ctx
+}
+
+// UnverifiedJWTIdentity returns an IdentityExtractor which reads the claims of a JWT, from the bearer token of the
+// Authorization header or else from one of the given cookies. The sub claim is used as ID, the tenant claim as Tenant,
+// the scope or scp claim as Scope and the role or roles claim as Role, multiple roles are joined by a comma.
+// The signature of the token is NOT verified, the Identity is only suitable for telemetry and never for authorization.
+func UnverifiedJWTIdentity(cookies ...string) IdentityExtractor {
+	return func(r *http.Request) (Identity, bool) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		for _, name := range cookies {
+			if found {
+				break
+			}
+			if cookie, err := r.Cookie(name); err == nil {
+				token, found = cookie.Value, true
+			}
+		}
+		if !found {
+			return Identity{}, false
+		}
+		return parseUnverifiedJWT(strings.TrimSpace(token))
+	}
+}
+
+// jwtClaims are the claims read by UnverifiedJWTIdentity, the scope and role claims are either a string or a list of strings.
+type jwtClaims struct {
+	Subject string          `json:"sub"`
+	Tenant  string          `json:"tenant"`
+	Scope   json.RawMessage `json:"scope"`
+	Scp     json.RawMessage `json:"scp"`
+	Role    json.RawMessage `json:"role"`
+	Roles   json.RawMessage `json:"roles"`
+}
+
+// parseUnverifiedJWT decodes the claims of a JWT without verifying its signature.
+func parseUnverifiedJWT(token string) (Identity, bool) {
+	if len(token) > maxTokenLength {
+		return Identity{}, false
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Identity{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return Identity{}, false
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Identity{}, false
+	}
+	identity := Identity{
+		ID:     claims.Subject,
+		Tenant: claims.Tenant,
+		Scope:  strings.Join(claimValues(claims.Scope, claims.Scp), " "),
+		Role:   strings.Join(claimValues(claims.Role, claims.Roles), ","),
+	}
+	return identity, identity != Identity{}
+}
+
+// claimValues returns the values of the first present claim, which is either a string or a list of strings.
+func claimValues(claims ...json.RawMessage) []string {
+	for _, claim := range claims {
+		if len(claim) == 0 {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(claim, &value); err == nil {
+			return []string{value}
+		}
+		var values []string
+		if err := json.Unmarshal(claim, &values); err == nil {
+			return values
+		}
+	}
+	return nil
+}
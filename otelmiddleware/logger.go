@@ -0,0 +1,38 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LoggerFactory places a request-scoped logger in the context.Context, the logger carries the trace context of the span
+// and the attributes describing the request. The ContextWithTracing methods of the otelslog, otelzerolog and otellogrus
+// loggers match this signature, handlers retrieve the logger using the FromContext function of the same package.
+type LoggerFactory func(ctx context.Context, span trace.Span, attributes []attribute.KeyValue) context.Context
+
+// loggerAttributes returns the method and, when already known, the route of the request.
+func loggerAttributes(r *http.Request, route string) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(r.Method)}
+	if route != "" {
+		attributes = append(attributes, semconv.HTTPRoute(route))
+	}
+	return attributes
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type testLoggerKey struct{}
+
+type testLogger struct {
+	spanContext trace.SpanContext
+	attributes  []attribute.KeyValue
+}
+
+func TestWithLoggerFactory(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	factory := func(ctx context.Context, span trace.Span, attributes []attribute.KeyValue) context.Context {
+		return context.WithValue(ctx, testLoggerKey{}, testLogger{spanContext: span.SpanContext(), attributes: attributes})
+	}
+
+	var logger testLogger
+	mux := http.NewServeMux()
+	mux.Handle("GET /users/{id}", TraceWithOptions(WithTracer(provider.Tracer("test-tracer")), WithLoggerFactory(factory))(
+		testHandler(func(w http.ResponseWriter, r *http.Request) {
+			logger, _ = r.Context().Value(testLoggerKey{}).(testLogger)
+			w.WriteHeader(http.StatusOK)
+		})))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/123", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 ended span, got: %d", len(spans))
+	}
+	if !logger.spanContext.Equal(spans[0].SpanContext()) {
+		t.Errorf("expected the logger to carry the span context %v, got: %v", spans[0].SpanContext(), logger.spanContext)
+	}
+	want := attribute.NewSet(attribute.String("http.request.method", "GET"), attribute.String("http.route", "/users/{id}"))
+	if got := attribute.NewSet(logger.attributes...); !got.Equals(&want) {
+		t.Errorf("expected the attributes %v, got: %v", want.ToSlice(), got.ToSlice())
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	// metric names and units as defined by the OpenTelemetry HTTP semantic conventions.
+	serverRequestDurationName  = "http.server.request.duration"
+	serverActiveRequestsName   = "http.server.active_requests"
+	serverRequestBodySizeName  = "http.server.request.body.size"
+	serverResponseBodySizeName = "http.server.response.body.size"
+	// serverRequestErrorsName is not part of the semantic conventions, it counts the requests classified as an error.
+	serverRequestErrorsName = "http.server.request.errors"
+	// serverRequestCancellationsName is not part of the semantic conventions, it counts the requests canceled while being served.
+	serverRequestCancellationsName = "http.server.request.cancellations"
+	// serverTimeToFirstByteName is not part of the semantic conventions, it measures the latency of streamed responses.
+	serverTimeToFirstByteName = "http.server.time_to_first_byte"
+)
+
+// durationBuckets are the explicit bucket boundaries, in seconds, advised for http.server.request.duration.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// serverMetrics holds the instruments that are recorded for every request passing through the middleware.
+type serverMetrics struct {
+	requestDuration      metric.Float64Histogram
+	activeRequests       metric.Int64UpDownCounter
+	requestBodySize      metric.Int64Histogram
+	responseBodySize     metric.Int64Histogram
+	timeToFirstByte      metric.Float64Histogram
+	requestErrors        metric.Int64Counter
+	requestCancellations metric.Int64Counter
+}
+
+// newServerMetrics creates the server instruments using a metric.Meter from the given metric.MeterProvider.
+// Errors returned while creating instruments are passed to the global otel error handler.
+func newServerMetrics(mp metric.MeterProvider) *serverMetrics {
+	meter := mp.Meter(instrumentationName, metric.WithInstrumentationVersion(version))
+	m := &serverMetrics{}
+
+	var err error
+	m.requestDuration, err = meter.Float64Histogram(serverRequestDurationName,
+		metric.WithDescription("Duration of HTTP server requests."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	)
+	handleErr(err)
+
+	m.activeRequests, err = meter.Int64UpDownCounter(serverActiveRequestsName,
+		metric.WithDescription("Number of active HTTP server requests."),
+		metric.WithUnit("{request}"),
+	)
+	handleErr(err)
+
+	m.requestBodySize, err = meter.Int64Histogram(serverRequestBodySizeName,
+		metric.WithDescription("Size of HTTP server request bodies."),
+		metric.WithUnit("By"),
+	)
+	handleErr(err)
+
+	m.responseBodySize, err = meter.Int64Histogram(serverResponseBodySizeName,
+		metric.WithDescription("Size of HTTP server response bodies."),
+		metric.WithUnit("By"),
+	)
+	handleErr(err)
+
+	m.timeToFirstByte, err = meter.Float64Histogram(serverTimeToFirstByteName,
+		metric.WithDescription("Time between the start of HTTP server requests and sending the response header."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	)
+	handleErr(err)
+
+	m.requestErrors, err = meter.Int64Counter(serverRequestErrorsName,
+		metric.WithDescription("Number of HTTP server requests classified as an error."),
+		metric.WithUnit("{request}"),
+	)
+	handleErr(err)
+
+	m.requestCancellations, err = meter.Int64Counter(serverRequestCancellationsName,
+		metric.WithDescription("Number of HTTP server requests canceled by the client or a deadline while being served."),
+		metric.WithUnit("{request}"),
+	)
+	handleErr(err)
+
+	return m
+}
+
+// requestStarted increments the active request counter, it returns a function that decrements the counter again.
+func (m *serverMetrics) requestStarted(ctx context.Context, attributes []attribute.KeyValue) func() {
+	set := metric.WithAttributeSet(attribute.NewSet(attributes...))
+	m.activeRequests.Add(ctx, 1, set)
+	return func() {
+		m.activeRequests.Add(ctx, -1, set)
+	}
+}
+
+// requestFinished records the duration, time to first byte and body sizes of a served request.
+// The requestBodySize is the Content-Length of the request, or the bytes read when the length is unknown.
+func (m *serverMetrics) requestFinished(ctx context.Context, requestBodySize int64, w WrapResponseWriter, elapsed time.Duration, attributes []attribute.KeyValue) {
+	set := metric.WithAttributeSet(attribute.NewSet(attributes...))
+
+	m.requestDuration.Record(ctx, elapsed.Seconds(), set)
+	if requestBodySize >= 0 {
+		m.requestBodySize.Record(ctx, requestBodySize, set)
+	}
+	m.responseBodySize.Record(ctx, int64(w.BytesWritten()), set)
+	if sw, ok := w.(streamingWriter); ok && sw.timeToFirstByte() > 0 {
+		m.timeToFirstByte.Record(ctx, sw.timeToFirstByte().Seconds(), set)
+	}
+}
+
+// requestFailed counts a request classified as an error, the error.type attribute is always part of the count.
+func (m *serverMetrics) requestFailed(ctx context.Context, attributes []attribute.KeyValue, errorType string) {
+	if errorType == "" {
+		errorType = "_OTHER"
+	}
+	attributes = append(slices.Clip(attributes), semconv.ErrorTypeKey.String(errorType))
+	m.requestErrors.Add(ctx, 1, metric.WithAttributeSet(attribute.NewSet(attributes...)))
+}
+
+// requestCanceled counts a request of which the context got canceled, the reason is part of the count.
+func (m *serverMetrics) requestCanceled(ctx context.Context, attributes []attribute.KeyValue, reason string) {
+	attributes = append(slices.Clip(attributes), cancellationKey.String(reason))
+	m.requestCancellations.Add(ctx, 1, metric.WithAttributeSet(attribute.NewSet(attributes...)))
+}
+
+func handleErr(err error) {
+	if err != nil {
+		otel.Handle(err)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.11.0"
+)
+
+func TestServerMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	handler := TraceWithOptions(WithMeterProvider(provider))(testHandler(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("body"))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics failed due to: %v", err)
+	}
+	if len(rm.ScopeMetrics) != 1 {
+		t.Fatalf("expected 1 scope, got: %d", len(rm.ScopeMetrics))
+	}
+
+	found := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		found[m.Name] = m.Data
+	}
+
+	duration, ok := found[serverRequestDurationName].(metricdata.Histogram[float64])
+	if !ok || len(duration.DataPoints) != 1 {
+		t.Fatalf("expected a single %s data point, got: %v", serverRequestDurationName, found[serverRequestDurationName])
+	}
+	dp := duration.DataPoints[0]
+	if v, _ := dp.Attributes.Value(semconv.HTTPStatusCodeKey); v.AsInt64() != http.StatusCreated {
+		t.Errorf("expected status code %d, got: %d", http.StatusCreated, v.AsInt64())
+	}
+	if v, _ := dp.Attributes.Value(semconv.HTTPMethodKey); v.AsString() != http.MethodPost {
+		t.Errorf("expected method %s, got: %s", http.MethodPost, v.AsString())
+	}
+
+	active, ok := found[serverActiveRequestsName].(metricdata.Sum[int64])
+	if !ok || len(active.DataPoints) != 1 || active.DataPoints[0].Value != 0 {
+		t.Errorf("expected no active requests after serving, got: %v", found[serverActiveRequestsName])
+	}
+
+	requestSize, ok := found[serverRequestBodySizeName].(metricdata.Histogram[int64])
+	if !ok || len(requestSize.DataPoints) != 1 || requestSize.DataPoints[0].Sum != 4 {
+		t.Errorf("expected a request body size of 4, got: %v", found[serverRequestBodySizeName])
+	}
+
+	responseSize, ok := found[serverResponseBodySizeName].(metricdata.Histogram[int64])
+	if !ok || len(responseSize.DataPoints) != 1 || responseSize.DataPoints[0].Sum != 7 {
+		t.Errorf("expected a response body size of 7, got: %v", found[serverResponseBodySizeName])
+	}
+}
+
+func TestServerMetricsUnknownMethod(t *testing.T) {
+	for _, mode := range []SemConvStability{SemConvStabilityOld, SemConvStabilityDuplicate} {
+		reader := sdkmetric.NewManualReader()
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+		handler := TraceWithOptions(WithMeterProvider(provider), WithSemConvStability(mode))(testHandler(func(http.ResponseWriter, *http.Request) {}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("RANDOMXYZ123", "/", nil))
+
+		rm := metricdata.ResourceMetrics{}
+		if err := reader.Collect(context.Background(), &rm); err != nil {
+			t.Fatalf("collecting metrics failed due to: %v", err)
+		}
+		for _, m := range rm.ScopeMetrics[0].Metrics {
+			var attributes []attribute.Set
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					attributes = append(attributes, dp.Attributes)
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					attributes = append(attributes, dp.Attributes)
+				}
+			case metricdata.Histogram[int64]:
+				for _, dp := range data.DataPoints {
+					attributes = append(attributes, dp.Attributes)
+				}
+			}
+			for _, set := range attributes {
+				if v, _ := set.Value(semconv.HTTPMethodKey); v.AsString() != "_OTHER" {
+					t.Errorf("expected %s to record http.method _OTHER in mode %d, got: %q", m.Name, mode, v.AsString())
+				}
+			}
+		}
+	}
+}
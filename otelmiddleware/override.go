@@ -0,0 +1,134 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SamplingPriorityKey is the span start attribute carrying the sampling priority set using WithRouteSamplingPriority.
+// A sampler honoring it samples a span with a priority above 0 and drops a span with a priority of 0.
+const SamplingPriorityKey = attribute.Key("sampling.priority")
+
+// RouteOption takes a routeConfig struct and applies changes.
+// It can be passed to the WithRouteOverride TraceOption function to configure the tracing of a route.
+type RouteOption func(*routeConfig)
+
+// routeConfig contains the per-route overrides of the traceConfig.
+type routeConfig struct {
+	attributes []attribute.KeyValue
+	priority   *int
+	disabled   bool
+	// slowThreshold overrides the threshold set using WithSlowRequestThreshold.
+	slowThreshold time.Duration
+}
+
+// routeOverrides matches requests to the routeConfig of the most specific pattern, using the matching rules of http.ServeMux.
+type routeOverrides struct {
+	mux     *http.ServeMux
+	configs map[string]*routeConfig
+}
+
+// WithRouteAttributes adds attributes to the spans of the route.
+func WithRouteAttributes(attributes ...attribute.KeyValue) RouteOption {
+	return func(c *routeConfig) {
+		c.attributes = append(c.attributes, attributes...)
+	}
+}
+
+// WithRouteSamplingPriority sets the sampling priority of the route. The priority is passed to the sampler as the
+// sampling.priority span start attribute, a priority above 0 samples the span and a priority of 0 drops it.
+// The sampler of the trace.TracerProvider has to honor the SamplingPriorityKey attribute, such as the sampler of the
+// github.com/vincentfree/opentelemetry/otelmiddleware/prioritysampler module.
+func WithRouteSamplingPriority(priority int) RouteOption {
+	return func(c *routeConfig) {
+		c.priority = &priority
+	}
+}
+
+// WithRouteTracingDisabled disables tracing of the route, the requests are served like requests excluded using WithFilter.
+func WithRouteTracingDisabled() RouteOption {
+	return func(c *routeConfig) {
+		c.disabled = true
+	}
+}
+
+// WithRouteSlowRequestThreshold sets the slow request threshold of the route, it takes precedence over the threshold
+// set using WithSlowRequestThreshold. The slow request logger of WithSlowRequestThreshold is also used for the route.
+func WithRouteSlowRequestThreshold(threshold time.Duration) RouteOption {
+	return func(c *routeConfig) {
+		c.slowThreshold = threshold
+	}
+}
+
+// add registers the RouteOption's for the pattern, options for an already registered pattern are merged.
+// The pattern uses the syntax of http.ServeMux, a malformed pattern or a pattern conflicting with a registered pattern
+// is skipped, the error is passed to the global otel error handler.
+func (o *routeOverrides) add(pattern string, opts []RouteOption) {
+	if o.mux == nil {
+		o.mux = http.NewServeMux()
+		o.configs = make(map[string]*routeConfig)
+	}
+	config, ok := o.configs[pattern]
+	if !ok {
+		if err := register(o.mux, pattern); err != nil {
+			otel.Handle(fmt.Errorf("otelmiddleware: route override %q skipped: %w", pattern, err))
+			return
+		}
+		config = &routeConfig{}
+		o.configs[pattern] = config
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+}
+
+// register adds the pattern to the http.ServeMux, the panic of http.ServeMux.Handle is returned as error.
+func register(mux *http.ServeMux, pattern string) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	mux.Handle(pattern, http.NotFoundHandler())
+	return nil
+}
+
+// match returns the routeConfig of the pattern matching the request, nil is returned when no pattern matches.
+func (o *routeOverrides) match(r *http.Request) *routeConfig {
+	if o == nil || o.mux == nil {
+		return nil
+	}
+	_, pattern := o.mux.Handler(r)
+	return o.configs[pattern]
+}
+
+// startOptions returns the trace.SpanStartOption's applying the attributes and sampling priority of the route.
+func (c *routeConfig) startOptions() []trace.SpanStartOption {
+	attributes := c.attributes
+	if c.priority != nil {
+		attributes = append(attributes[:len(attributes):len(attributes)], SamplingPriorityKey.Int(*c.priority))
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+	return []trace.SpanStartOption{trace.WithAttributes(attributes...)}
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithRouteOverride(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		path      string
+		spans     int
+		attribute attribute.KeyValue
+		priority  int64
+	}{
+		{desc: "route attributes", path: "/users/42", spans: 1, attribute: attribute.String("team", "accounts")},
+		{desc: "sampled by priority", path: "/checkout", spans: 1, priority: 1},
+		{desc: "dropped by priority", path: "/metrics", spans: 0},
+		{desc: "tracing disabled", path: "/internal/status", spans: 0},
+		{desc: "not overridden", path: "/other", spans: 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(
+				sdktrace.WithSpanProcessor(recorder),
+				// without a sampling priority nothing is sampled.
+				sdktrace.WithSampler(prioritySampler{base: sdktrace.NeverSample()}),
+			)
+
+			served := false
+			handler := TraceWithOptions(
+				WithTracer(provider.Tracer("test-tracer")),
+				WithRouteOverride("GET /users/{id}", WithRouteAttributes(attribute.String("team", "accounts")), WithRouteSamplingPriority(1)),
+				WithRouteOverride("/checkout", WithRouteSamplingPriority(1)),
+				WithRouteOverride("/metrics", WithRouteSamplingPriority(0)),
+				WithRouteOverride("/internal/", WithRouteTracingDisabled()),
+			)(testHandler(func(w http.ResponseWriter, r *http.Request) {
+				served = true
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tC.path, nil))
+
+			if !served {
+				t.Error("expected the request to be served")
+			}
+			spans := recorder.Ended()
+			if len(spans) != tC.spans {
+				t.Fatalf("expected %d spans, got %d", tC.spans, len(spans))
+			}
+			if tC.spans == 0 {
+				return
+			}
+			attrs := spans[0].Attributes()
+			if tC.attribute.Valid() && attributeValue(attrs, tC.attribute.Key) != tC.attribute.Value {
+				t.Errorf("expected attribute %s=%s", tC.attribute.Key, tC.attribute.Value.Emit())
+			}
+			if tC.priority != 0 && attributeValue(attrs, SamplingPriorityKey).AsInt64() != tC.priority {
+				t.Errorf("expected sampling priority %d", tC.priority)
+			}
+		})
+	}
+}
+
+func TestWithRouteOverrideMerged(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	handler := TraceWithOptions(
+		WithTracer(provider.Tracer("test-tracer")),
+		WithRouteOverride("/", WithRouteAttributes(attribute.String("a", "1"))),
+		WithRouteOverride("/", WithRouteAttributes(attribute.String("b", "2"))),
+	)(testHandler(func(http.ResponseWriter, *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	attrs := spans[0].Attributes()
+	if attributeValue(attrs, "a").AsString() != "1" || attributeValue(attrs, "b").AsString() != "2" {
+		t.Errorf("expected the attributes of both overrides, got %v", attrs)
+	}
+}
+
+func TestWithRouteOverrideConflict(t *testing.T) {
+	var errs []error
+	handler := otel.GetErrorHandler()
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		errs = append(errs, err)
+	}))
+	defer otel.SetErrorHandler(handler)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	// the second pattern conflicts with the first and is skipped instead of panicking.
+	traced := TraceWithOptions(
+		WithTracer(provider.Tracer("test-tracer")),
+		WithRouteOverride("/a/{x}", WithRouteAttributes(attribute.String("route", "x"))),
+		WithRouteOverride("/a/{y}", WithRouteAttributes(attribute.String("route", "y"))),
+	)(testHandler(func(http.ResponseWriter, *http.Request) {}))
+	traced.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a/1", nil))
+
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error for the conflicting pattern, got: %v", errs)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if got := attributeValue(spans[0].Attributes(), "route").AsString(); got != "x" {
+		t.Errorf("expected the attributes of the first pattern, got %q", got)
+	}
+}
+
+// prioritySampler samples the spans by their SamplingPriorityKey, other spans are passed to the base sampler.
+type prioritySampler struct {
+	base sdktrace.Sampler
+}
+
+func (s prioritySampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, attr := range p.Attributes {
+		if attr.Key == SamplingPriorityKey {
+			if attr.Value.AsInt64() > 0 {
+				return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample}
+			}
+			return sdktrace.SamplingResult{Decision: sdktrace.Drop}
+		}
+	}
+	return s.base.ShouldSample(p)
+}
+
+func (s prioritySampler) Description() string {
+	return "prioritySampler{" + s.base.Description() + "}"
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultPanicResponse writes a plain 500 Internal Server Error response.
+var defaultPanicResponse = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+})
+
+// serve passes the request to the next http.Handler. When panic handling is enabled a panic is recorded on the span,
+// after which it is either re-raised or answered with the configured response. It reports whether a panic was recovered.
+func (c *traceConfig) serve(next http.Handler, w WrapResponseWriter, r *http.Request, span trace.Span) (recovered bool) {
+	if !c.recordPanics {
+		next.ServeHTTP(w, r)
+		return false
+	}
+
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+		// http.ErrAbortHandler is used to abort a response on purpose, it is passed on to the http.Server untouched.
+		if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+			panic(rec)
+		}
+		recordPanic(span, rec)
+		if c.panicResponse == nil {
+			panic(rec)
+		}
+		// a response which is already (partially) sent can not be replaced, it is aborted so the client sees the failure.
+		if w.Status() != 0 || hijacked(w) {
+			panic(http.ErrAbortHandler)
+		}
+		c.panicResponse.ServeHTTP(w, r)
+		recovered = true
+	}()
+
+	next.ServeHTTP(w, r)
+	return false
+}
+
+// recordPanic records the recovered value as an exception event including the stack trace and sets the span status to error.
+func recordPanic(span trace.Span, rec any) {
+	message := fmt.Sprint(rec)
+	span.AddEvent(semconv.ExceptionEventName, trace.WithAttributes(
+		semconv.ExceptionType(fmt.Sprintf("%T", rec)),
+		semconv.ExceptionMessage(message),
+		semconv.ExceptionStacktrace(string(debug.Stack())),
+		semconv.ExceptionEscaped(true),
+	))
+	span.SetStatus(codes.Error, message)
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestPanicHandling(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		option      TraceOption
+		value       any
+		flush       bool
+		wantPanic   bool
+		wantAbort   bool
+		wantStatus  int
+		wantRecords bool
+	}{
+		{
+			desc:        "recovery with default response",
+			option:      WithPanicRecovery(nil),
+			value:       "boom",
+			wantStatus:  http.StatusInternalServerError,
+			wantRecords: true,
+		},
+		{
+			desc: "recovery with custom response",
+			option: WithPanicRecovery(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})),
+			value:       "boom",
+			wantStatus:  http.StatusServiceUnavailable,
+			wantRecords: true,
+		},
+		{
+			desc:        "recovery aborts a flushed response",
+			option:      WithPanicRecovery(nil),
+			value:       "boom",
+			flush:       true,
+			wantPanic:   true,
+			wantAbort:   true,
+			wantRecords: true,
+		},
+		{
+			desc:        "recording re-panics",
+			option:      WithPanicRecording(),
+			value:       "boom",
+			wantPanic:   true,
+			wantRecords: true,
+		},
+		{
+			desc:      "abort handler is passed on",
+			option:    WithPanicRecovery(nil),
+			value:     http.ErrAbortHandler,
+			wantPanic: true,
+			wantAbort: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")), tC.option)(
+				testHandler(func(w http.ResponseWriter, _ *http.Request) {
+					if tC.flush {
+						_, _ = w.Write([]byte("partial"))
+						w.(http.Flusher).Flush()
+					}
+					panic(tC.value)
+				}))
+
+			res := httptest.NewRecorder()
+			var value any
+			panicked := func() (panicked bool) {
+				defer func() {
+					value = recover()
+					panicked = value != nil
+				}()
+				handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+				return false
+			}()
+
+			if panicked != tC.wantPanic {
+				t.Fatalf("expected panic to be %t, but was: %t", tC.wantPanic, panicked)
+			}
+			if aborted := value == http.ErrAbortHandler; tC.wantPanic && aborted != tC.wantAbort {
+				t.Errorf("expected the response to be aborted %t, but was: %t", tC.wantAbort, aborted)
+			}
+			if !tC.wantPanic && res.Code != tC.wantStatus {
+				t.Errorf("expected status code %d, got: %d", tC.wantStatus, res.Code)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ended span, got: %d", len(spans))
+			}
+			// the sdk adds an exception event without a stack trace when a panic passes span.End, the middleware's event has one.
+			var event *sdktrace.Event
+			for i, e := range spans[0].Events() {
+				for _, attr := range e.Attributes {
+					if e.Name == semconv.ExceptionEventName && attr.Key == semconv.ExceptionStacktraceKey {
+						event = &spans[0].Events()[i]
+					}
+				}
+			}
+			if !tC.wantRecords {
+				if event != nil {
+					t.Errorf("expected no recorded panic, got: %v", event)
+				}
+				return
+			}
+			if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "boom" {
+				t.Errorf("expected an error status with the panic message, got: %v", spans[0].Status())
+			}
+			if event == nil {
+				t.Fatalf("expected an exception event, got: %v", spans[0].Events())
+			}
+			attrs := map[string]bool{}
+			for _, attr := range event.Attributes {
+				attrs[string(attr.Key)] = true
+				if attr.Key == semconv.ExceptionEscapedKey && !attr.Value.AsBool() {
+					t.Error("expected exception.escaped to be true")
+				}
+			}
+			for _, key := range []string{"exception.type", "exception.message", "exception.escaped"} {
+				if !attrs[key] {
+					t.Errorf("expected attribute %s on the exception event", key)
+				}
+			}
+		})
+	}
+}
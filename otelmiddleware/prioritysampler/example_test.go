@@ -0,0 +1,34 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prioritysampler_test
+
+import (
+	"net/http"
+
+	"github.com/vincentfree/opentelemetry/otelmiddleware"
+	"github.com/vincentfree/opentelemetry/otelmiddleware/prioritysampler"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func ExampleNew() {
+	// sample 1% of the requests, except for the checkout which is always sampled.
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(prioritysampler.New(sdktrace.TraceIDRatioBased(0.01))))
+	handler := otelmiddleware.TraceWithOptions(
+		otelmiddleware.WithTracer(provider.Tracer("example-tracer")),
+		otelmiddleware.WithRouteOverride("POST /checkout", otelmiddleware.WithRouteSamplingPriority(1)),
+	)
+	// pass a http.Handler to extend it with Tracing functionality.
+	http.Handle("/", handler(http.NotFoundHandler()))
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prioritysampler provides a sdktrace.Sampler honoring the sampling priority of the otelmiddleware routes.
+//
+// The otelmiddleware package only depends on the OpenTelemetry API, the sampler lives in this module as it depends on the SDK.
+// A route configured using otelmiddleware.WithRouteSamplingPriority passes its priority to the sampler as the
+// otelmiddleware.SamplingPriorityKey span start attribute. The sampler returned by New samples a span with a priority
+// above 0, drops a span with a priority of 0 and passes any other span to the base sampler.
+//
+//	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(prioritysampler.New(sdktrace.TraceIDRatioBased(0.01))))
+//	handler := otelmiddleware.TraceWithOptions(
+//		otelmiddleware.WithTracer(provider.Tracer("my-service")),
+//		otelmiddleware.WithRouteOverride("POST /checkout", otelmiddleware.WithRouteSamplingPriority(1)),
+//	)
+package prioritysampler // import "github.com/vincentfree/opentelemetry/otelmiddleware/prioritysampler"
+
+import (
+	"github.com/vincentfree/opentelemetry/otelmiddleware"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a sdktrace.Sampler which honors the sampling priority set using otelmiddleware.WithRouteSamplingPriority.
+// A span with a priority above 0 is sampled, a span with a priority of 0 is dropped, other spans are passed to the base sampler.
+func New(base sdktrace.Sampler) sdktrace.Sampler {
+	return prioritySampler{base: base}
+}
+
+type prioritySampler struct {
+	base sdktrace.Sampler
+}
+
+func (s prioritySampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, attr := range p.Attributes {
+		if attr.Key != otelmiddleware.SamplingPriorityKey {
+			continue
+		}
+		decision := sdktrace.Drop
+		if attr.Value.AsInt64() > 0 {
+			decision = sdktrace.RecordAndSample
+		}
+		return sdktrace.SamplingResult{
+			Decision:   decision,
+			Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+	return s.base.ShouldSample(p)
+}
+
+func (s prioritySampler) Description() string {
+	return "PrioritySampler{" + s.base.Description() + "}"
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prioritysampler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vincentfree/opentelemetry/otelmiddleware"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNew(t *testing.T) {
+	sampler := New(sdktrace.AlwaysSample())
+	if got := sampler.Description(); got != "PrioritySampler{AlwaysOnSampler}" {
+		t.Errorf("unexpected description %q", got)
+	}
+	testCases := []struct {
+		desc       string
+		attributes []attribute.KeyValue
+		decision   sdktrace.SamplingDecision
+	}{
+		{desc: "no priority", decision: sdktrace.RecordAndSample},
+		{desc: "priority 0", attributes: []attribute.KeyValue{otelmiddleware.SamplingPriorityKey.Int(0)}, decision: sdktrace.Drop},
+		{desc: "priority 2", attributes: []attribute.KeyValue{otelmiddleware.SamplingPriorityKey.Int(2)}, decision: sdktrace.RecordAndSample},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result := sampler.ShouldSample(sdktrace.SamplingParameters{Attributes: tC.attributes})
+			if result.Decision != tC.decision {
+				t.Errorf("expected decision %v, got %v", tC.decision, result.Decision)
+			}
+		})
+	}
+}
+
+func TestRouteSamplingPriority(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		// without a sampling priority nothing is sampled.
+		sdktrace.WithSampler(New(sdktrace.NeverSample())),
+	)
+	handler := otelmiddleware.TraceWithOptions(
+		otelmiddleware.WithTracer(provider.Tracer("test-tracer")),
+		otelmiddleware.WithRouteOverride("/checkout", otelmiddleware.WithRouteSamplingPriority(1)),
+		otelmiddleware.WithRouteOverride("/metrics", otelmiddleware.WithRouteSamplingPriority(0)),
+	)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for _, path := range []string{"/checkout", "/metrics", "/other"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected only the checkout to be sampled, got %d spans", len(spans))
+	}
+	if got := spans[0].Name(); got != "GET" {
+		t.Errorf("unexpected span name %q", got)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// proxyErrorEvent marks an error passed to the ErrorHandler of a httputil.ReverseProxy.
+const proxyErrorEvent = "http.proxy.error"
+
+// WrapReverseProxy returns a copy of the httputil.ReverseProxy which traces the upstream hop. Every proxied request is
+// sent using a Transport, which creates a client span under the server span of TraceWithOptions and injects the span
+// context into the outgoing request. The client span records the upstream address and status code. Errors passed to
+// the ErrorHandler are recorded on the server span before the ErrorHandler of the proxy is called.
+// It accepts the same TraceOption's as TraceWithOptions.
+//
+//	proxy := otelmiddleware.WrapReverseProxy(httputil.NewSingleHostReverseProxy(upstream))
+//	handler := otelmiddleware.Trace(proxy)
+func WrapReverseProxy(proxy *httputil.ReverseProxy, opt ...TraceOption) *httputil.ReverseProxy {
+	wrapped := *proxy
+	transport := NewTransport(nil, opt...)
+	transport.base = &upstreamTransport{base: proxy.Transport, semconv: transport.config.semconv}
+	wrapped.Transport = transport
+
+	errorHandler := proxy.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = defaultProxyErrorHandler(proxy.ErrorLog)
+	}
+	wrapped.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		span := trace.SpanFromContext(r.Context())
+		span.AddEvent(proxyErrorEvent, trace.WithAttributes(
+			semconv.ExceptionType(fmt.Sprintf("%T", err)),
+			semconv.ExceptionMessage(err.Error()),
+		))
+		errorHandler(w, r, err)
+	}
+	return &wrapped
+}
+
+// defaultProxyErrorHandler behaves like the ErrorHandler used by a httputil.ReverseProxy without one, it logs the error and answers with a 502.
+func defaultProxyErrorHandler(logger *log.Logger) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, _ *http.Request, err error) {
+		if logger != nil {
+			logger.Printf("http: proxy error: %v", err)
+		} else {
+			log.Printf("http: proxy error: %v", err)
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}
+}
+
+// upstreamTransport records the address of the upstream connection on the client span, when base is nil http.DefaultTransport is used.
+type upstreamTransport struct {
+	base    http.RoundTripper
+	semconv SemConvStability
+}
+
+func (t *upstreamTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if span := trace.SpanFromContext(r.Context()); span.IsRecording() {
+		ctx := httptrace.WithClientTrace(r.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				span.SetAttributes(t.semconv.peerAttributes(info.Conn.RemoteAddr().String())...)
+			},
+		})
+		r = r.WithContext(ctx)
+	}
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWrapReverseProxy(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	propagator := propagation.TraceContext{}
+
+	var upstreamParent trace.SpanContext
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamParent = trace.SpanContextFromContext(propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header)))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("upstream"))
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	opts := []TraceOption{WithTracer(provider.Tracer("test-tracer")), WithPropagator(propagator), WithSemConvStability(SemConvStabilityNew)}
+	proxy := WrapReverseProxy(httputil.NewSingleHostReverseProxy(target), opts...)
+	handler := TraceWithOptions(opts...)(proxy)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/resource", nil))
+	if w.Code != http.StatusAccepted || w.Body.String() != "upstream" {
+		t.Fatalf("expected the upstream response, got %d %q", w.Code, w.Body.String())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	client, server := spans[0], spans[1]
+	if client.SpanKind() != trace.SpanKindClient || server.SpanKind() != trace.SpanKindServer {
+		t.Fatalf("expected a client and a server span, got %v and %v", client.SpanKind(), server.SpanKind())
+	}
+	if !client.Parent().Equal(server.SpanContext()) {
+		t.Error("expected the client span to be a child of the server span")
+	}
+	if upstreamParent.SpanID() != client.SpanContext().SpanID() {
+		t.Error("expected the client span context to be propagated to the upstream")
+	}
+	attrs := client.Attributes()
+	if got := attributeValue(attrs, semconv.HTTPResponseStatusCodeKey).AsInt64(); got != http.StatusAccepted {
+		t.Errorf("expected upstream status %d, got %d", http.StatusAccepted, got)
+	}
+	if got := attributeValue(attrs, semconv.NetworkPeerAddressKey).AsString(); got != "127.0.0.1" {
+		t.Errorf("expected upstream address 127.0.0.1, got %q", got)
+	}
+	if got := attributeValue(attrs, semconv.NetworkPeerPortKey).AsInt64(); strconv.FormatInt(got, 10) != target.Port() {
+		t.Errorf("expected upstream port %s, got %d", target.Port(), got)
+	}
+}
+
+func TestWrapReverseProxyError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// a closed server refuses the connection.
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	target, _ := url.Parse(upstream.URL)
+	upstream.Close()
+
+	reverseProxy := httputil.NewSingleHostReverseProxy(target)
+	reverseProxy.ErrorLog = log.New(io.Discard, "", 0)
+	proxy := WrapReverseProxy(reverseProxy, WithTracer(provider.Tracer("test-tracer")))
+	handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")))(proxy)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/resource", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("expected a 502, got %d", w.Code)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	client, server := spans[0], spans[1]
+	if client.Status().Code != codes.Error {
+		t.Error("expected the client span to record the error")
+	}
+	if server.Status().Code != codes.Error {
+		t.Error("expected the server span to be an error")
+	}
+	events := server.Events()
+	if len(events) != 1 || events[0].Name != proxyErrorEvent {
+		t.Fatalf("expected a %s event, got %v", proxyErrorEvent, events)
+	}
+	if got := attributeValue(events[0].Attributes, semconv.ExceptionMessageKey).AsString(); got == "" {
+		t.Error("expected the error message")
+	}
+}
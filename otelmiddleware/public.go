@@ -0,0 +1,47 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// baggageHeader is the header used by the W3C baggage propagator.
+const baggageHeader = "baggage"
+
+// public reports whether the request is served as a public endpoint.
+func (c *traceConfig) public(r *http.Request) bool {
+	return c.publicEndpointFn != nil && c.publicEndpointFn(r)
+}
+
+// untrust removes the extracted remote span context from the context of a public endpoint request,
+// it returns the span start options which start a new root span linked to the remote span context.
+// The incoming baggage, including the W3C baggage header, is removed as well when configured.
+func (c *traceConfig) untrust(ctx context.Context, r *http.Request) (context.Context, []trace.SpanStartOption) {
+	opts := []trace.SpanStartOption{trace.WithNewRoot()}
+	if remote := trace.SpanContextFromContext(ctx); remote.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: remote}))
+	}
+	ctx = trace.ContextWithSpanContext(ctx, trace.SpanContext{})
+	if c.dropPublicBaggage {
+		ctx = baggage.ContextWithoutBaggage(ctx)
+		r.Header.Del(baggageHeader)
+	}
+	return ctx, opts
+}
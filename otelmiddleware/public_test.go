@@ -0,0 +1,100 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPublicEndpoint(t *testing.T) {
+	const remoteTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testCases := []struct {
+		desc        string
+		options     []TraceOption
+		wantLinked  bool
+		wantBaggage bool
+	}{
+		{
+			desc:        "trusted endpoint",
+			wantBaggage: true,
+		},
+		{
+			desc:        "public endpoint",
+			options:     []TraceOption{WithPublicEndpoint()},
+			wantLinked:  true,
+			wantBaggage: true,
+		},
+		{
+			desc:       "public endpoint without baggage",
+			options:    []TraceOption{WithPublicEndpoint(), WithPublicBaggageDropped()},
+			wantLinked: true,
+		},
+		{
+			desc: "public endpoint function",
+			options: []TraceOption{WithPublicEndpointFn(func(r *http.Request) bool {
+				return r.URL.Path == "/"
+			})},
+			wantLinked:  true,
+			wantBaggage: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			options := append([]TraceOption{
+				WithTracer(provider.Tracer("test-tracer")),
+				WithPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})),
+			}, tC.options...)
+
+			var member string
+			handler := TraceWithOptions(options...)(testHandler(func(w http.ResponseWriter, r *http.Request) {
+				member = baggage.FromContext(r.Context()).Member("user").Value()
+				w.WriteHeader(http.StatusOK)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("traceparent", "00-"+remoteTraceID+"-00f067aa0ba902b7-01")
+			r.Header.Set("baggage", "user=alice")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ended span, got: %d", len(spans))
+			}
+			span := spans[0]
+			if linked := span.SpanContext().TraceID().String() != remoteTraceID; linked != tC.wantLinked {
+				t.Errorf("expected a new root span to be %t, got trace ID: %s", tC.wantLinked, span.SpanContext().TraceID())
+			}
+			if tC.wantLinked {
+				if span.Parent().IsValid() {
+					t.Errorf("expected no parent, got: %v", span.Parent())
+				}
+				if len(span.Links()) != 1 || span.Links()[0].SpanContext.TraceID().String() != remoteTraceID {
+					t.Errorf("expected a link to the remote span context, got: %v", span.Links())
+				}
+			}
+			if hasBaggage := member == "alice"; hasBaggage != tC.wantBaggage {
+				t.Errorf("expected baggage to be present %t, got: '%s'", tC.wantBaggage, member)
+			}
+		})
+	}
+}
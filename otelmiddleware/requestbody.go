@@ -0,0 +1,126 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// requestBodyReadDurationKey records the seconds spent reading the request body.
+	requestBodyReadDurationKey = attribute.Key("http.request.body.read_duration")
+	// requestBodyLimitKey records the limit of a http.MaxBytesReader which was exceeded.
+	requestBodyLimitKey = attribute.Key("http.request.body.limit")
+	// requestBodyReadErrorEvent marks the first error returned while reading the request body.
+	requestBodyReadErrorEvent = "http.request.body.read_error"
+)
+
+// maxBytesReaderType is the type of the io.ReadCloser returned by http.MaxBytesReader.
+var maxBytesReaderType = reflect.TypeOf(http.MaxBytesReader(nil, http.NoBody, 0))
+
+// countingBody wraps the request body to count the bytes read and measure the time spent reading.
+// The first read error, for example a client abort or a http.MaxBytesError, is recorded as a span event.
+type countingBody struct {
+	io.ReadCloser
+	span     trace.Span
+	bytes    atomic.Int64
+	duration atomic.Int64
+	failed   atomic.Bool
+}
+
+// newCountingBody replaces the body of the request with a countingBody, requests without a body are left as is.
+func newCountingBody(r *http.Request, span trace.Span) *countingBody {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	body := &countingBody{ReadCloser: r.Body, span: span}
+	r.Body = body
+	return body
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := b.ReadCloser.Read(p)
+	b.duration.Add(int64(time.Since(start)))
+	b.bytes.Add(int64(n))
+	if err != nil && err != io.EOF && b.failed.CompareAndSwap(false, true) {
+		b.recordError(err)
+	}
+	return n, err
+}
+
+// recordError adds the read error as a span event, the limit of an exceeded http.MaxBytesReader is included.
+func (b *countingBody) recordError(err error) {
+	attributes := []attribute.KeyValue{
+		semconv.ExceptionType(fmt.Sprintf("%T", err)),
+		semconv.ExceptionMessage(err.Error()),
+		semconv.HTTPRequestBodySize(int(b.bytes.Load())),
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		attributes = append(attributes, requestBodyLimitKey.Int64(maxBytesErr.Limit))
+	}
+	b.span.AddEvent(requestBodyReadErrorEvent, trace.WithAttributes(attributes...))
+}
+
+// checkLimit records the http.MaxBytesError of a http.MaxBytesReader which the handler wrapped around the request body,
+// the countingBody does not see this error as it is returned by the outer reader. A zero length read returns the error of
+// an exceeded http.MaxBytesReader without reading from the body. A limit applied to a copy of the request is not seen.
+func (b *countingBody) checkLimit(r *http.Request) {
+	if b == nil || b.failed.Load() || reflect.TypeOf(r.Body) != maxBytesReaderType {
+		return
+	}
+	var maxBytesErr *http.MaxBytesError
+	if _, err := r.Body.Read(nil); errors.As(err, &maxBytesErr) && b.failed.CompareAndSwap(false, true) {
+		b.recordError(err)
+	}
+}
+
+// bytesRead returns the number of bytes read from the body, a nil countingBody has read nothing.
+func (b *countingBody) bytesRead() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.bytes.Load()
+}
+
+// requestBodySize returns the size of the request body, the Content-Length when it is known and otherwise the bytes read.
+func requestBodySize(r *http.Request, body *countingBody) int64 {
+	if r.ContentLength >= 0 {
+		return r.ContentLength
+	}
+	return body.bytesRead()
+}
+
+// record adds the bytes read and the time spent reading the request body to the span.
+func (b *countingBody) record(span trace.Span) {
+	if b == nil || b.bytes.Load() == 0 && !b.failed.Load() {
+		return
+	}
+	span.SetAttributes(
+		semconv.HTTPRequestBodySize(int(b.bytes.Load())),
+		requestBodyReadDurationKey.Float64(time.Duration(b.duration.Load()).Seconds()),
+	)
+}
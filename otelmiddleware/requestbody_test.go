@@ -0,0 +1,160 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestRequestBodyInstrumentation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+
+	handler := TraceWithOptions(
+		WithTracer(provider.Tracer("test-tracer")),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)(testHandler(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	// a chunked upload has no Content-Length, the size is only known once the body is read.
+	r := httptest.NewRequest(http.MethodPost, "/upload", io.NopCloser(strings.NewReader("chunked upload")))
+	r.ContentLength = -1
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	attrs := spans[0].Attributes()
+	if got := attributeValue(attrs, semconv.HTTPRequestBodySizeKey).AsInt64(); got != 14 {
+		t.Errorf("expected a request body size of 14, got %d", got)
+	}
+	if got := attributeValue(attrs, requestBodyReadDurationKey).AsFloat64(); got <= 0 {
+		t.Errorf("expected a read duration, got %f", got)
+	}
+	if events := spans[0].Events(); len(events) != 0 {
+		t.Errorf("expected no read error events, got %v", events)
+	}
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics failed due to: %v", err)
+	}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name != serverRequestBodySizeName {
+			continue
+		}
+		if size, ok := m.Data.(metricdata.Histogram[int64]); !ok || len(size.DataPoints) != 1 || size.DataPoints[0].Sum != 14 {
+			t.Errorf("expected a request body size of 14, got: %v", m.Data)
+		}
+		return
+	}
+	t.Errorf("expected the %s metric", serverRequestBodySizeName)
+}
+
+func TestRequestBodyReadError(t *testing.T) {
+	testCases := []struct {
+		desc string
+		// wrap applies the limit either before the middleware or in the handler.
+		wrap func(traced func(http.Handler) http.Handler, handler http.Handler) http.Handler
+		// size is the number of bytes read from the counted body, the http.MaxBytesReader of the handler reads one byte past its limit.
+		size int64
+	}{
+		{
+			desc: "limit before the middleware",
+			wrap: func(traced func(http.Handler) http.Handler, handler http.Handler) http.Handler {
+				next := traced(handler)
+				return testHandler(func(w http.ResponseWriter, r *http.Request) {
+					r.Body = http.MaxBytesReader(w, r.Body, 4)
+					next.ServeHTTP(w, r)
+				})
+			},
+			size: 4,
+		},
+		{
+			desc: "limit in the handler",
+			wrap: func(traced func(http.Handler) http.Handler, handler http.Handler) http.Handler {
+				return traced(testHandler(func(w http.ResponseWriter, r *http.Request) {
+					r.Body = http.MaxBytesReader(w, r.Body, 4)
+					handler.ServeHTTP(w, r)
+				}))
+			},
+			size: 5,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			handler := tC.wrap(TraceWithOptions(WithTracer(provider.Tracer("test-tracer"))), testHandler(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := io.ReadAll(r.Body); err != nil {
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+				}
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("too large")))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			events := spans[0].Events()
+			if len(events) != 1 || events[0].Name != requestBodyReadErrorEvent {
+				t.Fatalf("expected a %s event, got %v", requestBodyReadErrorEvent, events)
+			}
+			if got := attributeValue(events[0].Attributes, semconv.ExceptionTypeKey).AsString(); got != "*http.MaxBytesError" {
+				t.Errorf("expected a *http.MaxBytesError, got %q", got)
+			}
+			if got := attributeValue(events[0].Attributes, requestBodyLimitKey).AsInt64(); got != 4 {
+				t.Errorf("expected a limit of 4, got %d", got)
+			}
+			if got := attributeValue(spans[0].Attributes(), semconv.HTTPRequestBodySizeKey).AsInt64(); got != tC.size {
+				t.Errorf("expected a request body size of %d, got %d", tC.size, got)
+			}
+		})
+	}
+}
+
+func TestRequestWithoutBody(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")))(testHandler(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != http.NoBody {
+			t.Error("expected the body to be left as is")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if attributeValue(spans[0].Attributes(), semconv.HTTPRequestBodySizeKey).Type() != attribute.INVALID {
+		t.Error("expected no request body size")
+	}
+}
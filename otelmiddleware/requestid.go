@@ -0,0 +1,161 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// RequestIDBaggageKey is the baggage member carrying the request ID. The otelslog, otelzerolog and otellogrus loggers
+	// read it to add the request ID to their logs, outgoing requests propagate it to downstream services.
+	RequestIDBaggageKey = "request.id"
+	// defaultRequestIDHeader is the header used to accept and echo the request ID.
+	defaultRequestIDHeader = "X-Request-ID"
+	// requestIDKey is the span attribute holding the request ID.
+	requestIDKey = attribute.Key("http.request.id")
+	// maxRequestIDLength is the maximum length of an accepted incoming request ID.
+	maxRequestIDLength = 128
+)
+
+// requestIDContextKey is the context.Context key under which the request ID is stored.
+type requestIDContextKey struct{}
+
+// RequestIDGenerator generates the request ID of a request which did not carry one, the span context of the server span is passed.
+type RequestIDGenerator func(sc trace.SpanContext) string
+
+// RequestIDOption takes a requestIDConfig struct and applies changes.
+// It can be passed to the WithRequestID TraceOption function to configure the request ID.
+type RequestIDOption func(*requestIDConfig)
+
+// requestIDConfig contains the configuration for the request ID.
+type requestIDConfig struct {
+	header    string
+	generator RequestIDGenerator
+}
+
+// newRequestIDConfig applies the RequestIDOption's and sets default values for absent configuration.
+func newRequestIDConfig(opts []RequestIDOption) *requestIDConfig {
+	config := &requestIDConfig{
+		header:    defaultRequestIDHeader,
+		generator: UUIDv7RequestID,
+	}
+	for _, o := range opts {
+		o(config)
+	}
+	return config
+}
+
+// WithRequestIDHeader sets the header used to accept and echo the request ID, the default is X-Request-ID.
+func WithRequestIDHeader(header string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.header = header
+	}
+}
+
+// WithRequestIDGenerator sets the RequestIDGenerator used when a request does not carry a request ID.
+// The package provides UUIDv7RequestID, the default, and TraceIDRequestID.
+func WithRequestIDGenerator(generator RequestIDGenerator) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.generator = generator
+	}
+}
+
+// UUIDv7RequestID is a RequestIDGenerator which generates a time ordered UUID version 7.
+func UUIDv7RequestID(_ trace.SpanContext) string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+// TraceIDRequestID is a RequestIDGenerator which uses the trace ID as request ID, so the request ID can be used to look up the trace.
+// A UUID version 7 is generated when the span context is invalid.
+func TraceIDRequestID(sc trace.SpanContext) string {
+	if !sc.TraceID().IsValid() {
+		return UUIDv7RequestID(sc)
+	}
+	return sc.TraceID().String()
+}
+
+// RequestIDFromContext returns the request ID assigned by TraceWithOptions when the WithRequestID TraceOption function is used.
+// It falls back to the request ID in the baggage, which is propagated by upstream services.
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDContextKey{}).(string); ok {
+		return id
+	}
+	return baggage.FromContext(ctx).Member(RequestIDBaggageKey).Value()
+}
+
+// resolve returns the incoming request ID, a new request ID is generated when it is absent or invalid.
+func (c *requestIDConfig) resolve(r *http.Request, sc trace.SpanContext) string {
+	if id := r.Header.Get(c.header); validRequestID(id) {
+		return id
+	}
+	return c.generator(sc)
+}
+
+// validRequestID reports whether an incoming request ID is safe to record, only printable ASCII is accepted.
+// This keeps clients from injecting log lines or flooding the span with large values.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// withRequestID returns a copy of ctx which carries the request ID, both as context value and as baggage member.
+func withRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDContextKey{}, id)
+	return withBaggageMember(ctx, RequestIDBaggageKey, id)
+}
+
+// withBaggageMember returns a copy of ctx of which the baggage carries the member, errors are passed to the global otel error handler.
+func withBaggageMember(ctx context.Context, key, value string) context.Context {
+	member, err := baggage.NewMemberRaw(key, value)
+	if err != nil {
+		otel.Handle(err)
+		return ctx
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		otel.Handle(err)
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// assign resolves the request ID of the request and shares it through the span, baggage and request context.
+// The request ID is set on the request, so it is forwarded by proxies, and echoed on the response.
+func (c *requestIDConfig) assign(ctx context.Context, span trace.Span, w http.ResponseWriter, r *http.Request) context.Context {
+	id := c.resolve(r, span.SpanContext())
+	span.SetAttributes(requestIDKey.String(id))
+	r.Header.Set(c.header, id)
+	w.Header().Set(c.header, id)
+	return withRequestID(ctx, id)
+}
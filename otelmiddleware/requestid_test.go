@@ -0,0 +1,159 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithRequestID(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		incoming string
+		opts     []RequestIDOption
+		check    func(t *testing.T, id string, sc trace.SpanContext)
+	}{
+		{
+			desc:     "incoming request ID",
+			incoming: "legacy-client-42",
+			check: func(t *testing.T, id string, _ trace.SpanContext) {
+				if id != "legacy-client-42" {
+					t.Errorf("expected the incoming request ID, got %q", id)
+				}
+			},
+		},
+		{
+			desc: "generated uuid v7",
+			check: func(t *testing.T, id string, _ trace.SpanContext) {
+				if parsed, err := uuid.Parse(id); err != nil || parsed.Version() != 7 {
+					t.Errorf("expected a uuid v7, got %q", id)
+				}
+			},
+		},
+		{
+			desc:     "invalid incoming request ID",
+			incoming: "injected\nlog line",
+			check: func(t *testing.T, id string, _ trace.SpanContext) {
+				if _, err := uuid.Parse(id); err != nil {
+					t.Errorf("expected a generated request ID, got %q", id)
+				}
+			},
+		},
+		{
+			desc:     "too long incoming request ID",
+			incoming: strings.Repeat("a", maxRequestIDLength+1),
+			check: func(t *testing.T, id string, _ trace.SpanContext) {
+				if _, err := uuid.Parse(id); err != nil {
+					t.Errorf("expected a generated request ID, got %q", id)
+				}
+			},
+		},
+		{
+			desc: "derived from the trace ID",
+			opts: []RequestIDOption{WithRequestIDGenerator(TraceIDRequestID)},
+			check: func(t *testing.T, id string, sc trace.SpanContext) {
+				if id != sc.TraceID().String() {
+					t.Errorf("expected the trace ID %s, got %q", sc.TraceID(), id)
+				}
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			var fromContext, fromBaggage, fromHeader string
+			handler := TraceWithOptions(
+				WithTracer(provider.Tracer("test-tracer")),
+				WithRequestID(tC.opts...),
+			)(testHandler(func(w http.ResponseWriter, r *http.Request) {
+				fromContext = RequestIDFromContext(r.Context())
+				fromBaggage = baggage.FromContext(r.Context()).Member(RequestIDBaggageKey).Value()
+				fromHeader = r.Header.Get("X-Request-ID")
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tC.incoming != "" {
+				r.Header.Set("X-Request-ID", tC.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			id := attributeValue(spans[0].Attributes(), requestIDKey).AsString()
+			tC.check(t, id, spans[0].SpanContext())
+			if fromContext != id || fromBaggage != id || fromHeader != id {
+				t.Errorf("expected request ID %q in the context, baggage and request header, got %q, %q and %q", id, fromContext, fromBaggage, fromHeader)
+			}
+			if got := w.Header().Get("X-Request-ID"); got != id {
+				t.Errorf("expected request ID %q on the response, got %q", id, got)
+			}
+		})
+	}
+}
+
+func TestWithRequestIDHeader(t *testing.T) {
+	handler := TraceWithOptions(WithRequestID(WithRequestIDHeader("X-Correlation-ID")))(testHandler(func(http.ResponseWriter, *http.Request) {}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Correlation-ID", "abc")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if got := w.Header().Get("X-Correlation-ID"); got != "abc" {
+		t.Errorf("expected the request ID on the configured header, got %q", got)
+	}
+	if got := w.Header().Get("X-Request-ID"); got != "" {
+		t.Errorf("expected no X-Request-ID header, got %q", got)
+	}
+}
+
+func TestWithRequestIDLoggerFactory(t *testing.T) {
+	var id string
+	factory := func(ctx context.Context, _ trace.Span, _ []attribute.KeyValue) context.Context {
+		id = baggage.FromContext(ctx).Member(RequestIDBaggageKey).Value()
+		return ctx
+	}
+	handler := TraceWithOptions(WithRequestID(), WithLoggerFactory(factory))(testHandler(func(http.ResponseWriter, *http.Request) {}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Request-ID", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if id != "abc" {
+		t.Errorf("expected the request ID in the context of the LoggerFactory, got %q", id)
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	if got := RequestIDFromContext(context.Background()); got != "" {
+		t.Errorf("expected no request ID, got %q", got)
+	}
+	member, _ := baggage.NewMemberRaw(RequestIDBaggageKey, "upstream")
+	bag, _ := baggage.New(member)
+	if got := RequestIDFromContext(baggage.ContextWithBaggage(context.Background(), bag)); got != "upstream" {
+		t.Errorf("expected the request ID of the baggage, got %q", got)
+	}
+}
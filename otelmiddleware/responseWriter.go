@@ -7,9 +7,11 @@ package otelmiddleware
 
 import (
 	"bufio"
+	"bytes"
 	"io"
 	"net"
 	"net/http"
+	"time"
 )
 
 // NewWrapResponseWriter wraps an http.ResponseWriter, returning a proxy that allows you to
@@ -17,7 +19,7 @@ import (
 func NewWrapResponseWriter(w http.ResponseWriter, protoMajor int) WrapResponseWriter {
 	_, fl := w.(http.Flusher)
 
-	bw := basicWriter{ResponseWriter: w}
+	bw := basicWriter{ResponseWriter: w, start: time.Now()}
 
 	if protoMajor == 2 {
 		_, ps := w.(http.Pusher)
@@ -79,12 +81,26 @@ type basicWriter struct {
 	bytes       int
 	tee         io.Writer
 	discard     bool
+	// headerHooks are called once, right before the header is written.
+	headerHooks []func(http.Header)
+	// hijackHooks are called once the connection is hijacked, they can wrap the returned net.Conn.
+	hijackHooks []func(net.Conn) net.Conn
+	// upgrade reports whether the request asked for a protocol upgrade, hijacked marks a connection taken over by the handler.
+	upgrade  bool
+	hijacked bool
+	// start, firstByte and lastFlush are used to measure streamed responses.
+	start     time.Time
+	firstByte time.Time
+	lastFlush time.Time
+	flushes   int
 }
 
 func (b *basicWriter) WriteHeader(code int) {
 	if !b.wroteHeader {
+		b.runHeaderHooks()
 		b.code = code
 		b.wroteHeader = true
+		b.firstByte = time.Now()
 		if !b.discard {
 			b.ResponseWriter.WriteHeader(code)
 		}
@@ -137,15 +153,173 @@ func (b *basicWriter) Discard() {
 	b.discard = true
 }
 
+// timeToFirstByte returns the time between wrapping the http.ResponseWriter and sending the header, or 0 if it has not yet been sent.
+func (b *basicWriter) timeToFirstByte() time.Duration {
+	if b.firstByte.IsZero() {
+		return 0
+	}
+	return b.firstByte.Sub(b.start)
+}
+
+// firstByteTime returns the time the header was sent.
+func (b *basicWriter) firstByteTime() time.Time {
+	return b.firstByte
+}
+
+// flushCount returns the number of times the response has been flushed.
+func (b *basicWriter) flushCount() int {
+	return b.flushes
+}
+
+// lastFlushTime returns the time of the last flush, or the zero time.Time if the response has not been flushed.
+func (b *basicWriter) lastFlushTime() time.Time {
+	return b.lastFlush
+}
+
+// flush sends the buffered response to the client, a flush before the header is written sends a 200 OK header.
+func (b *basicWriter) flush() {
+	now := time.Now()
+	if !b.wroteHeader {
+		b.runHeaderHooks()
+		b.code = http.StatusOK
+		b.wroteHeader = true
+		b.firstByte = now
+	}
+	b.flushes++
+	b.lastFlush = now
+	b.ResponseWriter.(http.Flusher).Flush()
+}
+
+// addHeaderHook registers a function which can modify the header right before it is written.
+func (b *basicWriter) addHeaderHook(fn func(http.Header)) {
+	b.headerHooks = append(b.headerHooks, fn)
+}
+
+// runHeaderHooks calls the registered header hooks once.
+func (b *basicWriter) runHeaderHooks() {
+	hooks := b.headerHooks
+	b.headerHooks = nil
+	for _, fn := range hooks {
+		fn(b.Header())
+	}
+}
+
+// implicitHeader runs the header hooks when the handler returned without writing the header,
+// the http.Server writes an implicit 200 OK header once the handler returns.
+func (b *basicWriter) implicitHeader() {
+	if !b.wroteHeader {
+		b.runHeaderHooks()
+	}
+}
+
+// hijack takes over the connection of the proxied http.ResponseWriter, the connection is passed to the hijack hooks.
+// The bufio.ReadWriter is only replaced when a hook returned a different net.Conn. When no header was written the response of an upgrade request is reported as 101 Switching Protocols,
+// the status code of any other hijacked connection is left at 0 as the response is unknown.
+func (b *basicWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := b.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return conn, rw, err
+	}
+	b.hijacked = true
+	if !b.wroteHeader {
+		// the response is written on the connection, bypassing the http.ResponseWriter.
+		if b.upgrade {
+			b.code = http.StatusSwitchingProtocols
+		}
+		b.wroteHeader = true
+	}
+	hooks := b.hijackHooks
+	b.hijackHooks = nil
+	hooked := conn
+	for _, fn := range hooks {
+		hooked = fn(hooked)
+	}
+	if hooked == conn {
+		return conn, rw, nil
+	}
+	return hooked, rewrapReadWriter(rw, hooked), nil
+}
+
+// rewrapReadWriter returns a bufio.ReadWriter on top of conn, the data already buffered by rw is preserved.
+func rewrapReadWriter(rw *bufio.ReadWriter, conn net.Conn) *bufio.ReadWriter {
+	var reader io.Reader = conn
+	if n := rw.Reader.Buffered(); n > 0 {
+		buffered, _ := rw.Reader.Peek(n)
+		reader = io.MultiReader(bytes.NewReader(bytes.Clone(buffered)), conn)
+	}
+	_ = rw.Writer.Flush()
+	return bufio.NewReadWriter(bufio.NewReaderSize(reader, rw.Reader.Size()), bufio.NewWriterSize(conn, rw.Writer.Size()))
+}
+
+// addHijackHook registers a function which is called with the connection once it is hijacked.
+func (b *basicWriter) addHijackHook(fn func(net.Conn) net.Conn) {
+	b.hijackHooks = append(b.hijackHooks, fn)
+}
+
+// headerHooker is implemented by the writers returned by NewWrapResponseWriter.
+type headerHooker interface {
+	addHeaderHook(fn func(http.Header))
+	implicitHeader()
+}
+
+// addHeaderHook registers fn on the WrapResponseWriter, fn is called right before the header is written.
+func addHeaderHook(w WrapResponseWriter, fn func(http.Header)) {
+	if hw, ok := w.(headerHooker); ok {
+		hw.addHeaderHook(fn)
+	}
+}
+
+// implicitHeader runs the header hooks of the WrapResponseWriter when the handler returned without writing the header.
+func implicitHeader(w WrapResponseWriter) {
+	if hw, ok := w.(headerHooker); ok {
+		hw.implicitHeader()
+	}
+}
+
+// expectUpgrade marks the request as an upgrade request, a hijack then reports 101 Switching Protocols.
+func (b *basicWriter) expectUpgrade() {
+	b.upgrade = true
+}
+
+// isHijacked reports whether the handler hijacked the connection.
+func (b *basicWriter) isHijacked() bool {
+	return b.hijacked
+}
+
+// hijackHooker is implemented by the writers returned by NewWrapResponseWriter.
+type hijackHooker interface {
+	addHijackHook(fn func(net.Conn) net.Conn)
+	expectUpgrade()
+	isHijacked() bool
+}
+
+// addHijackHook registers fn on the WrapResponseWriter, fn is called once the connection is hijacked by the handler.
+func addHijackHook(w WrapResponseWriter, fn func(net.Conn) net.Conn) {
+	if hw, ok := w.(hijackHooker); ok {
+		hw.addHijackHook(fn)
+	}
+}
+
+// expectUpgrade marks the WrapResponseWriter of an upgrade request, a hijacked connection then reports 101 Switching Protocols.
+func expectUpgrade(w WrapResponseWriter, r *http.Request) {
+	if hw, ok := w.(hijackHooker); ok && isUpgradeRequest(r) {
+		hw.expectUpgrade()
+	}
+}
+
+// hijacked reports whether the connection of the WrapResponseWriter was hijacked by the handler.
+func hijacked(w WrapResponseWriter) bool {
+	hw, ok := w.(hijackHooker)
+	return ok && hw.isHijacked()
+}
+
 // flushWriter ...
 type flushWriter struct {
 	basicWriter
 }
 
 func (f *flushWriter) Flush() {
-	f.wroteHeader = true
-	fl := f.basicWriter.ResponseWriter.(http.Flusher)
-	fl.Flush()
+	f.basicWriter.flush()
 }
 
 var _ http.Flusher = &flushWriter{}
@@ -156,8 +330,7 @@ type hijackWriter struct {
 }
 
 func (f *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	hj := f.basicWriter.ResponseWriter.(http.Hijacker)
-	return hj.Hijack()
+	return f.basicWriter.hijack()
 }
 
 var _ http.Hijacker = &hijackWriter{}
@@ -168,14 +341,11 @@ type flushHijackWriter struct {
 }
 
 func (f *flushHijackWriter) Flush() {
-	f.wroteHeader = true
-	fl := f.basicWriter.ResponseWriter.(http.Flusher)
-	fl.Flush()
+	f.basicWriter.flush()
 }
 
 func (f *flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	hj := f.basicWriter.ResponseWriter.(http.Hijacker)
-	return hj.Hijack()
+	return f.basicWriter.hijack()
 }
 
 var _ http.Flusher = &flushHijackWriter{}
@@ -190,14 +360,11 @@ type httpFancyWriter struct {
 }
 
 func (f *httpFancyWriter) Flush() {
-	f.wroteHeader = true
-	fl := f.basicWriter.ResponseWriter.(http.Flusher)
-	fl.Flush()
+	f.basicWriter.flush()
 }
 
 func (f *httpFancyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	hj := f.basicWriter.ResponseWriter.(http.Hijacker)
-	return hj.Hijack()
+	return f.basicWriter.hijack()
 }
 
 func (f *http2FancyWriter) Push(target string, opts *http.PushOptions) error {
@@ -206,9 +373,8 @@ func (f *http2FancyWriter) Push(target string, opts *http.PushOptions) error {
 
 func (f *httpFancyWriter) ReadFrom(r io.Reader) (int64, error) {
 	if f.basicWriter.tee != nil {
-		n, err := io.Copy(&f.basicWriter, r)
-		f.basicWriter.bytes += int(n)
-		return n, err
+		// Write already counts the bytes.
+		return io.Copy(&f.basicWriter, r)
 	}
 	rf := f.basicWriter.ResponseWriter.(io.ReaderFrom)
 	f.basicWriter.maybeWriteHeader()
@@ -231,9 +397,7 @@ type http2FancyWriter struct {
 }
 
 func (f *http2FancyWriter) Flush() {
-	f.wroteHeader = true
-	fl := f.basicWriter.ResponseWriter.(http.Flusher)
-	fl.Flush()
+	f.basicWriter.flush()
 }
 
 var _ http.Flusher = &http2FancyWriter{}
\ No newline at end of file
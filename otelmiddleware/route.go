@@ -0,0 +1,90 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// routeKey is the context.Context key under which the routeState of a request is stored.
+type routeKey struct{}
+
+// routeState holds the route template of a request. It is stored as a pointer in the request context
+// so the route can be tagged by a http.Handler further down the chain and read back by the middleware.
+type routeState struct {
+	route string
+}
+
+// withRouteState returns a copy of ctx which carries an empty routeState.
+func withRouteState(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, &routeState{})
+}
+
+// TagRoute wraps a http.Handler and tags the request with the given route template, for example "/users/{id}".
+// The route is used by TraceWithOptions for the span name and the http.route attribute.
+// This is useful for routers that don't set http.Request.Pattern.
+func TagRoute(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if state, ok := r.Context().Value(routeKey{}).(*routeState); ok {
+			state.route = route
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RouteFromRequest returns the route template of the request.
+// A route tagged using TagRoute takes precedence over the pattern matched by a http.ServeMux,
+// an empty string is returned when no route is known.
+func RouteFromRequest(r *http.Request) string {
+	if state, ok := r.Context().Value(routeKey{}).(*routeState); ok && state.route != "" {
+		return state.route
+	}
+	return patternRoute(r.Pattern)
+}
+
+// patternRoute strips the method and host from a http.ServeMux pattern, "GET example.com/users/{id}" becomes "/users/{id}".
+func patternRoute(pattern string) string {
+	if pattern == "" {
+		return ""
+	}
+	if _, after, found := strings.Cut(pattern, " "); found {
+		pattern = strings.TrimLeft(after, " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// defaultSpanNameFormatter names the span after the method and route template, "GET /users/{id}".
+// When no route is known only the method is used and an unknown method is replaced by "HTTP",
+// this keeps the number of span names bounded.
+func defaultSpanNameFormatter(r *http.Request) string {
+	method := spanMethod(r.Method)
+	if route := RouteFromRequest(r); route != "" {
+		return method + " " + route
+	}
+	return method
+}
+
+// spanMethod returns the method used in a span name, an unknown method is replaced by "HTTP".
+func spanMethod(method string) string {
+	if normalized := normalizeMethod(method); normalized != "_OTHER" {
+		return normalized
+	}
+	return "HTTP"
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.11.0"
+)
+
+func TestSpanNames(t *testing.T) {
+	ok := testHandler(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("GET /users/{id}", ok)
+	mux.Handle("/tagged/", TagRoute("/tagged/{name}", ok))
+
+	testCases := []struct {
+		desc      string
+		method    string
+		target    string
+		options   []TraceOption
+		wantName  string
+		wantRoute string
+	}{
+		{
+			desc:      "ServeMux pattern",
+			target:    "/users/123?x=y",
+			wantName:  "GET /users/{id}",
+			wantRoute: "/users/{id}",
+		},
+		{
+			desc:      "TagRoute",
+			target:    "/tagged/abc",
+			wantName:  "GET /tagged/{name}",
+			wantRoute: "/tagged/{name}",
+		},
+		{
+			desc:     "unmatched route",
+			target:   "/unknown/123",
+			wantName: "GET",
+		},
+		{
+			desc:     "unknown method",
+			method:   "RANDOMXYZ123",
+			target:   "/unknown/123",
+			wantName: "HTTP",
+		},
+		{
+			desc:      "unknown method with route",
+			method:    "RANDOMXYZ123",
+			target:    "/tagged/abc",
+			wantName:  "HTTP /tagged/{name}",
+			wantRoute: "/tagged/{name}",
+		},
+		{
+			desc:   "custom formatter",
+			target: "/users/123",
+			options: []TraceOption{WithSpanNameFormatter(func(r *http.Request) string {
+				return "custom " + RouteFromRequest(r)
+			})},
+			wantName:  "custom /users/{id}",
+			wantRoute: "/users/{id}",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			options := append([]TraceOption{WithTracer(provider.Tracer("test-tracer"))}, tC.options...)
+
+			method := http.MethodGet
+			if tC.method != "" {
+				method = tC.method
+			}
+			handler := TraceWithOptions(options...)(mux)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, tC.target, nil))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ended span, got: %d", len(spans))
+			}
+			if spans[0].Name() != tC.wantName {
+				t.Errorf("expected span name '%s', got: '%s'", tC.wantName, spans[0].Name())
+			}
+			var route string
+			for _, attr := range spans[0].Attributes() {
+				if attr.Key == semconv.HTTPRouteKey {
+					route = attr.Value.AsString()
+				}
+			}
+			if route != tC.wantRoute {
+				t.Errorf("expected http.route '%s', got: '%s'", tC.wantRoute, route)
+			}
+		})
+	}
+}
+
+func TestPatternRoute(t *testing.T) {
+	testCases := map[string]string{
+		"":                              "",
+		"/":                             "/",
+		"/users/{id}":                   "/users/{id}",
+		"GET /users/{id}":               "/users/{id}",
+		"GET example.com/users/{id}":    "/users/{id}",
+		"POST  example.com/users/{id}/": "/users/{id}/",
+	}
+	for pattern, want := range testCases {
+		if got := patternRoute(pattern); got != want {
+			t.Errorf("patternRoute(%q) should be '%s', but was: '%s'", pattern, want, got)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	oldsemconv "go.opentelemetry.io/otel/semconv/v1.11.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// semConvStabilityOptIn is the environment variable used by OpenTelemetry to opt in to the stable semantic conventions.
+const semConvStabilityOptIn = "OTEL_SEMCONV_STABILITY_OPT_IN"
+
+// SemConvStability selects which HTTP semantic conventions are emitted by the middleware and the Transport.
+// The zero value resolves the mode from the OTEL_SEMCONV_STABILITY_OPT_IN environment variable.
+type SemConvStability int
+
+const (
+	// SemConvStabilityOld emits the v1.11.0 attributes such as http.method and net.peer.ip.
+	// It is used when OTEL_SEMCONV_STABILITY_OPT_IN does not contain "http" or "http/dup".
+	SemConvStabilityOld SemConvStability = iota + 1
+	// SemConvStabilityNew emits the stable attributes such as http.request.method and client.address.
+	// It is used when OTEL_SEMCONV_STABILITY_OPT_IN contains "http".
+	SemConvStabilityNew
+	// SemConvStabilityDuplicate emits both the v1.11.0 and the stable attributes, this allows dashboards to migrate gradually.
+	// It is used when OTEL_SEMCONV_STABILITY_OPT_IN contains "http/dup".
+	SemConvStabilityDuplicate
+)
+
+// semConvStabilityFromEnv resolves the SemConvStability from the OTEL_SEMCONV_STABILITY_OPT_IN environment variable.
+// The variable holds a comma separated list, "http/dup" takes precedence over "http".
+func semConvStabilityFromEnv() SemConvStability {
+	mode := SemConvStabilityOld
+	for _, value := range strings.Split(os.Getenv(semConvStabilityOptIn), ",") {
+		switch strings.TrimSpace(value) {
+		case "http/dup":
+			return SemConvStabilityDuplicate
+		case "http":
+			mode = SemConvStabilityNew
+		}
+	}
+	return mode
+}
+
+func (s SemConvStability) emitOld() bool {
+	return s == SemConvStabilityOld || s == SemConvStabilityDuplicate
+}
+
+func (s SemConvStability) emitNew() bool {
+	return s == SemConvStabilityNew || s == SemConvStabilityDuplicate
+}
+
+// serverRequestAttributes returns the attributes describing an incoming request on a server span.
+// The client is the resolved client address, which differs from the peer address behind trusted proxies.
+func (s SemConvStability) serverRequestAttributes(r *http.Request, route, client string) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	if s.emitOld() {
+		attributes = append(attributes, oldsemconv.NetAttributesFromHTTPRequest("tcp", r)...)
+		attributes = append(attributes, oldsemconv.EndUserAttributesFromHTTPRequest(r)...)
+		// the v1.11.0 http.client_ip attribute is taken from the X-Forwarded-For header unconditionally, it is replaced by the resolved client.
+		for _, kv := range oldsemconv.HTTPServerAttributesFromHTTPRequest(r.Host, route, r) {
+			if kv.Key != oldsemconv.HTTPClientIPKey {
+				attributes = append(attributes, kv)
+			}
+		}
+		if peer, _ := splitHostPort(r.RemoteAddr); client != "" && client != peer {
+			attributes = append(attributes, oldsemconv.HTTPClientIPKey.String(client))
+		}
+	}
+	if s.emitNew() {
+		attributes = append(attributes, methodAttributes(r.Method)...)
+		attributes = append(attributes,
+			semconv.URLScheme(scheme(r)),
+			semconv.URLPath(r.URL.Path),
+		)
+		if r.URL.RawQuery != "" {
+			attributes = append(attributes, semconv.URLQuery(r.URL.RawQuery))
+		}
+		attributes = append(attributes, hostAttributes(r.Host)...)
+		if client != "" {
+			attributes = append(attributes, semconv.ClientAddress(client))
+		}
+		if host, port := splitHostPort(r.RemoteAddr); host != "" {
+			attributes = append(attributes, semconv.NetworkPeerAddress(host))
+			if port > 0 {
+				attributes = append(attributes, semconv.NetworkPeerPort(port))
+			}
+		}
+		if ua := r.UserAgent(); ua != "" {
+			attributes = append(attributes, semconv.UserAgentOriginal(ua))
+		}
+		if route != "" && !s.emitOld() {
+			// the v1.11.0 attributes already contain the http.route attribute.
+			attributes = append(attributes, semconv.HTTPRoute(route))
+		}
+	}
+	return attributes
+}
+
+// clientRequestAttributes returns the attributes describing an outgoing request on a client span.
+func (s SemConvStability) clientRequestAttributes(r *http.Request) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	if s.emitOld() {
+		attributes = append(attributes, oldsemconv.HTTPClientAttributesFromHTTPRequest(r)...)
+	}
+	if s.emitNew() {
+		attributes = append(attributes, methodAttributes(r.Method)...)
+		// strip the credentials from the url before it is recorded.
+		u := *r.URL
+		u.User = nil
+		attributes = append(attributes, semconv.URLFull(u.String()))
+		host := r.URL.Host
+		if r.Host != "" {
+			host = r.Host
+		}
+		attributes = append(attributes, hostAttributes(host)...)
+	}
+	return attributes
+}
+
+// responseAttributes returns the attributes describing the response status code.
+func (s SemConvStability) responseAttributes(statusCode int) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	if s.emitOld() {
+		attributes = append(attributes, oldsemconv.HTTPAttributesFromHTTPStatusCode(statusCode)...)
+	}
+	if s.emitNew() && statusCode > 0 {
+		attributes = append(attributes, semconv.HTTPResponseStatusCode(statusCode))
+	}
+	return attributes
+}
+
+// peerAttributes returns the attributes describing the remote address of a connection.
+func (s SemConvStability) peerAttributes(addr string) []attribute.KeyValue {
+	host, port := splitHostPort(addr)
+	if host == "" {
+		return nil
+	}
+	var attributes []attribute.KeyValue
+	if s.emitOld() {
+		attributes = append(attributes, oldsemconv.NetPeerIPKey.String(host))
+		if port > 0 {
+			attributes = append(attributes, oldsemconv.NetPeerPortKey.Int(port))
+		}
+	}
+	if s.emitNew() {
+		attributes = append(attributes, semconv.NetworkPeerAddress(host))
+		if port > 0 {
+			attributes = append(attributes, semconv.NetworkPeerPort(port))
+		}
+	}
+	return attributes
+}
+
+// errorTypeAttributes returns the error.type attribute, it is only part of the stable semantic conventions.
+func (s SemConvStability) errorTypeAttributes(errorType string) []attribute.KeyValue {
+	if !s.emitNew() || errorType == "" {
+		return nil
+	}
+	return []attribute.KeyValue{semconv.ErrorTypeKey.String(errorType)}
+}
+
+// routeAttributes returns the http.route attribute, the key is the same in both versions of the semantic conventions.
+func (s SemConvStability) routeAttributes(route string) []attribute.KeyValue {
+	if route == "" {
+		return nil
+	}
+	return []attribute.KeyValue{semconv.HTTPRoute(route)}
+}
+
+// metricRequestAttributes returns the low cardinality request attributes used for the server metrics.
+// The method is normalized in both versions of the semantic conventions, a client chosen method would otherwise create a time series.
+func (s SemConvStability) metricRequestAttributes(r *http.Request) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	if s.emitOld() {
+		attributes = append(attributes, oldsemconv.HTTPMethodKey.String(normalizeMethod(r.Method)))
+	}
+	if s.emitNew() {
+		attributes = append(attributes, semconv.HTTPRequestMethodKey.String(normalizeMethod(r.Method)), semconv.URLScheme(scheme(r)))
+	}
+	return attributes
+}
+
+// metricResponseAttributes returns the low cardinality response attributes used for the server metrics.
+func (s SemConvStability) metricResponseAttributes(statusCode int) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	if s.emitOld() {
+		attributes = append(attributes, oldsemconv.HTTPStatusCodeKey.Int(statusCode))
+	}
+	if s.emitNew() && statusCode > 0 {
+		attributes = append(attributes, semconv.HTTPResponseStatusCode(statusCode))
+	}
+	return attributes
+}
+
+// knownMethods contains the methods defined in RFC9110 and RFC5789, other methods are recorded as _OTHER.
+var knownMethods = map[string]struct{}{
+	http.MethodConnect: {},
+	http.MethodDelete:  {},
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodOptions: {},
+	http.MethodPatch:   {},
+	http.MethodPost:    {},
+	http.MethodPut:     {},
+	http.MethodTrace:   {},
+}
+
+// normalizeMethod bounds the cardinality of the http.request.method attribute.
+func normalizeMethod(method string) string {
+	if _, ok := knownMethods[method]; ok {
+		return method
+	}
+	return "_OTHER"
+}
+
+// methodAttributes returns the http.request.method attribute, an unknown method is also recorded as http.request.method_original.
+func methodAttributes(method string) []attribute.KeyValue {
+	normalized := normalizeMethod(method)
+	if normalized == method {
+		return []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(method)}
+	}
+	return []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(normalized), semconv.HTTPRequestMethodOriginal(method)}
+}
+
+// hostAttributes returns the server.address and server.port attributes for the given host.
+func hostAttributes(hostport string) []attribute.KeyValue {
+	host, port := splitHostPort(hostport)
+	if host == "" {
+		return nil
+	}
+	attributes := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if port > 0 {
+		attributes = append(attributes, semconv.ServerPort(port))
+	}
+	return attributes
+}
+
+// splitHostPort splits a host with an optional port, the port is -1 when absent or invalid.
+func splitHostPort(hostport string) (string, int) {
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		// no port present.
+		return strings.Trim(hostport, "[]"), -1
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return host, -1
+	}
+	return host, port
+}
+
+// scheme returns the url scheme of an incoming request.
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// protocolVersion returns the network.protocol.version of a request, for example "1.1" or "2".
+func protocolVersion(r *http.Request) string {
+	if r.ProtoMajor >= 2 && r.ProtoMinor == 0 {
+		return strconv.Itoa(r.ProtoMajor)
+	}
+	return strconv.Itoa(r.ProtoMajor) + "." + strconv.Itoa(r.ProtoMinor)
+}
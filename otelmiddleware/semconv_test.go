@@ -0,0 +1,101 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSemConvStabilityFromEnv(t *testing.T) {
+	testCases := map[string]SemConvStability{
+		"":                   SemConvStabilityOld,
+		"database":           SemConvStabilityOld,
+		"http":               SemConvStabilityNew,
+		"database, http":     SemConvStabilityNew,
+		"http,http/dup":      SemConvStabilityDuplicate,
+		"database,http/dup ": SemConvStabilityDuplicate,
+	}
+	for value, want := range testCases {
+		t.Setenv(semConvStabilityOptIn, value)
+		if got := semConvStabilityFromEnv(); got != want {
+			t.Errorf("%s=%q should resolve to %d, but was: %d", semConvStabilityOptIn, value, want, got)
+		}
+	}
+}
+
+func TestSemConvStability(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		mode    SemConvStability
+		present []attribute.Key
+		absent  []attribute.Key
+	}{
+		{
+			desc:    "old",
+			mode:    SemConvStabilityOld,
+			present: []attribute.Key{"http.method", "http.status_code", "net.peer.ip"},
+			absent:  []attribute.Key{"http.request.method", "http.url", "error.type"},
+		},
+		{
+			desc: "new",
+			mode: SemConvStabilityNew,
+			present: []attribute.Key{"http.request.method", "url.path", "server.address", "client.address",
+				"http.response.status_code", "error.type"},
+			absent: []attribute.Key{"http.method", "http.status_code", "net.peer.ip"},
+		},
+		{
+			desc:    "duplicate",
+			mode:    SemConvStabilityDuplicate,
+			present: []attribute.Key{"http.method", "http.status_code", "http.request.method", "http.response.status_code"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")), WithSemConvStability(tC.mode))(
+				testHandler(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(http.StatusInternalServerError)
+				}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/123", nil))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ended span, got: %d", len(spans))
+			}
+			keys := map[attribute.Key]bool{}
+			for _, attr := range spans[0].Attributes() {
+				keys[attr.Key] = true
+			}
+			for _, key := range tC.present {
+				if !keys[key] {
+					t.Errorf("expected attribute %s to be present", key)
+				}
+			}
+			for _, key := range tC.absent {
+				if keys[key] {
+					t.Errorf("expected attribute %s to be absent", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	// serverOpenConnectionsName is not part of the semantic conventions, it mirrors http.client.open_connections.
+	serverOpenConnectionsName = "http.server.open_connections"
+	// serverConnectionDurationName is not part of the semantic conventions, it mirrors http.client.connection.duration.
+	serverConnectionDurationName = "http.server.connection.duration"
+	// serverTLSHandshakeErrorsName is not part of the semantic conventions, it counts the TLS handshakes started by a
+	// ClientHello which did not complete.
+	serverTLSHandshakeErrorsName = "http.server.tls.handshake.errors"
+	// connectionReusedKey records whether the request was served on a connection which already served a request.
+	connectionReusedKey = attribute.Key("http.connection.reused")
+)
+
+// connectionBuckets are the explicit bucket boundaries, in seconds, used for http.server.connection.duration.
+var connectionBuckets = []float64{0.01, 0.02, 0.05, 0.1, 0.2, 0.5, 1, 2, 5, 10, 30, 60, 300}
+
+// serverConnKey is the context.Context key under which the serverConn of a connection is stored.
+type serverConnKey struct{}
+
+// serverConn holds the number of requests served on a connection, it is stored in the connection context by InstrumentServer.
+type serverConn struct {
+	requests atomic.Int64
+}
+
+// connectionMetrics holds the instruments recorded for the connections of a http.Server.
+type connectionMetrics struct {
+	openConnections    metric.Int64UpDownCounter
+	connectionDuration metric.Float64Histogram
+	handshakeErrors    metric.Int64Counter
+}
+
+// newConnectionMetrics creates the connection instruments using a metric.Meter from the given metric.MeterProvider.
+// Errors returned while creating instruments are passed to the global otel error handler.
+func newConnectionMetrics(mp metric.MeterProvider) *connectionMetrics {
+	meter := mp.Meter(instrumentationName, metric.WithInstrumentationVersion(version))
+	m := &connectionMetrics{}
+
+	var err error
+	m.openConnections, err = meter.Int64UpDownCounter(serverOpenConnectionsName,
+		metric.WithDescription("Number of open HTTP server connections, by state."),
+		metric.WithUnit("{connection}"),
+	)
+	handleErr(err)
+
+	m.connectionDuration, err = meter.Float64Histogram(serverConnectionDurationName,
+		metric.WithDescription("Duration of HTTP server connections."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(connectionBuckets...),
+	)
+	handleErr(err)
+
+	m.handshakeErrors, err = meter.Int64Counter(serverTLSHandshakeErrorsName,
+		metric.WithDescription("Number of failed TLS handshakes of HTTP server connections."),
+		metric.WithUnit("{error}"),
+	)
+	handleErr(err)
+
+	return m
+}
+
+// InstrumentServer installs ConnState and ConnContext hooks on the http.Server, the hooks already present are still called.
+// It records the http.server.open_connections metric, with an http.connection.state of active or idle, a new connection
+// counts as idle until it serves its first request. Once a connection is closed or hijacked its duration is recorded as
+// http.server.connection.duration, including the network.protocol.version, tls.protocol.version and tls.cipher of TLS
+// connections. Request spans created by TraceWithOptions record whether the connection was reused.
+// When the server has a TLSConfig, a GetConfigForClient callback is chained to learn which connections sent a ClientHello,
+// such a connection closed before the handshake completed is counted as http.server.tls.handshake.errors. Connections
+// which never start a handshake, such as port probes, are not counted. Set the TLSConfig, an empty tls.Config will do for
+// ListenAndServeTLS, to record the handshake errors.
+// InstrumentServer must be called before the server starts, the meter provider is configured using WithMeterProvider.
+func InstrumentServer(srv *http.Server, opt ...TraceOption) {
+	config := newTraceConfig(opt)
+	tracker := &connTracker{
+		metrics:    newConnectionMetrics(config.meterProvider),
+		conns:      make(map[net.Conn]*trackedConnState),
+		handshakes: make(map[net.Conn]struct{}),
+	}
+
+	if srv.TLSConfig != nil {
+		getConfigForClient := srv.TLSConfig.GetConfigForClient
+		srv.TLSConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			tracker.handshakeStarted(hello.Conn)
+			if getConfigForClient != nil {
+				return getConfigForClient(hello)
+			}
+			return nil, nil
+		}
+	}
+
+	connState := srv.ConnState
+	srv.ConnState = func(conn net.Conn, state http.ConnState) {
+		tracker.track(conn, state)
+		if connState != nil {
+			connState(conn, state)
+		}
+	}
+	connContext := srv.ConnContext
+	srv.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+		if connContext != nil {
+			ctx = connContext(ctx, conn)
+		}
+		return context.WithValue(ctx, serverConnKey{}, &serverConn{})
+	}
+}
+
+// connTracker follows the state of the connections of a http.Server.
+type connTracker struct {
+	metrics *connectionMetrics
+	mu      sync.Mutex
+	conns   map[net.Conn]*trackedConnState
+	// handshakes holds the underlying connections of the TLS connections which sent a ClientHello.
+	handshakes map[net.Conn]struct{}
+}
+
+// handshakeStarted marks the TLS handshake of the underlying connection as started.
+func (t *connTracker) handshakeStarted(conn net.Conn) {
+	t.mu.Lock()
+	t.handshakes[conn] = struct{}{}
+	t.mu.Unlock()
+}
+
+// trackedConnState holds the time a connection was opened and the http.connection.state it is counted in.
+type trackedConnState struct {
+	opened time.Time
+	state  attribute.KeyValue
+}
+
+// track updates the open connection count on every state transition, closed and hijacked connections record their duration.
+func (t *connTracker) track(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	var handshakeStarted bool
+	tlsConn, isTLS := conn.(*tls.Conn)
+	if isTLS && (state == http.StateHijacked || state == http.StateClosed) {
+		_, handshakeStarted = t.handshakes[tlsConn.NetConn()]
+		delete(t.handshakes, tlsConn.NetConn())
+	}
+	tracked, ok := t.conns[conn]
+	if !ok {
+		if state != http.StateNew {
+			// the connection was accepted before the server got instrumented.
+			t.mu.Unlock()
+			return
+		}
+		tracked = &trackedConnState{opened: time.Now()}
+		t.conns[conn] = tracked
+	}
+	previous := tracked.state
+	// a closed or hijacked connection is no longer counted in any state.
+	var next attribute.KeyValue
+	switch state {
+	case http.StateNew, http.StateIdle:
+		next = semconv.HTTPConnectionStateIdle
+	case http.StateActive:
+		next = semconv.HTTPConnectionStateActive
+	case http.StateHijacked, http.StateClosed:
+		delete(t.conns, conn)
+	}
+	tracked.state = next
+	t.mu.Unlock()
+
+	ctx := context.Background()
+	if previous != next {
+		if previous.Valid() {
+			t.metrics.openConnections.Add(ctx, -1, metric.WithAttributes(previous))
+		}
+		if next.Valid() {
+			t.metrics.openConnections.Add(ctx, 1, metric.WithAttributes(next))
+		}
+	}
+	if next.Valid() {
+		return
+	}
+
+	var attributes []attribute.KeyValue
+	if isTLS {
+		cs := tlsConn.ConnectionState()
+		if !cs.HandshakeComplete {
+			if handshakeStarted {
+				t.metrics.handshakeErrors.Add(ctx, 1)
+			}
+			return
+		}
+		attributes = append(attributes,
+			semconv.NetworkProtocolVersion(alpnProtocolVersion(cs.NegotiatedProtocol)),
+			semconv.TLSProtocolVersion(tlsVersion(cs.Version)),
+			semconv.TLSCipher(tls.CipherSuiteName(cs.CipherSuite)),
+		)
+	}
+	t.metrics.connectionDuration.Record(ctx, time.Since(tracked.opened).Seconds(), metric.WithAttributes(attributes...))
+}
+
+// connectionAttributes returns the attributes describing the connection of a request, for the spans of TraceWithOptions.
+// The network.protocol.version is recorded in every SemConvStability mode,
+// the connection reuse is only known when the http.Server is instrumented using InstrumentServer.
+func connectionAttributes(r *http.Request) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{semconv.NetworkProtocolVersion(protocolVersion(r))}
+	if conn, ok := r.Context().Value(serverConnKey{}).(*serverConn); ok {
+		attributes = append(attributes, connectionReusedKey.Bool(conn.requests.Add(1) > 1))
+	}
+	if r.TLS != nil {
+		attributes = append(attributes, tlsAttributes(r.TLS)...)
+	}
+	return attributes
+}
+
+// tlsAttributes returns the tls.* attributes describing the TLS connection of a request.
+func tlsAttributes(cs *tls.ConnectionState) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		semconv.TLSProtocolNameTLS,
+		semconv.TLSProtocolVersion(tlsVersion(cs.Version)),
+		semconv.TLSCipher(tls.CipherSuiteName(cs.CipherSuite)),
+		semconv.TLSResumed(cs.DidResume),
+	}
+	if cs.NegotiatedProtocol != "" {
+		attributes = append(attributes, semconv.TLSNextProtocol(cs.NegotiatedProtocol))
+	}
+	if cs.ServerName != "" {
+		attributes = append(attributes, semconv.TLSClientServerName(cs.ServerName))
+	}
+	return attributes
+}
+
+// tlsVersion formats a TLS version as recorded in tls.protocol.version, for example "1.3".
+func tlsVersion(version uint16) string {
+	return strings.TrimPrefix(tls.VersionName(version), "TLS ")
+}
+
+// alpnProtocolVersion returns the HTTP version negotiated using ALPN, a connection without ALPN uses HTTP/1.1.
+func alpnProtocolVersion(protocol string) string {
+	if protocol == "h2" {
+		return "2"
+	}
+	return "1.1"
+}
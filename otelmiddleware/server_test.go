@@ -0,0 +1,195 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestInstrumentServer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")))(testHandler(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.TLSConfig = &tls.Config{}
+	InstrumentServer(srv.Config, WithMeterProvider(meterProvider))
+	// httptest starts the listener using its own TLS field, the instrumented config is cloned from it.
+	srv.TLS = srv.Config.TLSConfig
+	srv.StartTLS()
+
+	// two requests, the body is drained so the keep-alive connection can be reused.
+	client := srv.Client()
+	for range 2 {
+		res, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+	// a client which does not trust the certificate aborts the handshake after its ClientHello.
+	if conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{}); err == nil {
+		_ = conn.Close()
+		t.Fatal("expected the handshake to fail")
+	}
+	// a port probe never starts a handshake and is not counted as handshake error.
+	probe, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	_ = probe.Close()
+	client.CloseIdleConnections()
+	// Close waits until every connection is closed.
+	srv.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	var newConnections uint64
+	for i, span := range spans {
+		attrs := span.Attributes()
+		reused := attributeValue(attrs, connectionReusedKey)
+		if reused.Type() != attribute.BOOL {
+			t.Errorf("expected request %d to have http.connection.reused", i)
+		}
+		if !reused.AsBool() {
+			newConnections++
+		}
+		if got := attributeValue(attrs, semconv.TLSProtocolVersionKey).AsString(); got != "1.3" {
+			t.Errorf("expected tls.protocol.version 1.3, got %q", got)
+		}
+		if got := attributeValue(attrs, semconv.TLSCipherKey).AsString(); got == "" {
+			t.Error("expected tls.cipher")
+		}
+	}
+	if attributeValue(spans[0].Attributes(), connectionReusedKey).AsBool() {
+		t.Error("expected the first request to be served on a new connection")
+	}
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics failed due to: %v", err)
+	}
+	found := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		found[m.Name] = m.Data
+	}
+
+	open, ok := found[serverOpenConnectionsName].(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("expected the %s metric, got %v", serverOpenConnectionsName, found[serverOpenConnectionsName])
+	}
+	for _, dp := range open.DataPoints {
+		if dp.Value != 0 {
+			t.Errorf("expected no open connections after closing the server, got %d %v", dp.Value, dp.Attributes.ToSlice())
+		}
+	}
+
+	// every connection which served a request completed its handshake and records its duration.
+	duration, ok := found[serverConnectionDurationName].(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("expected the %s metric, got %v", serverConnectionDurationName, found[serverConnectionDurationName])
+	}
+	var connections uint64
+	for _, dp := range duration.DataPoints {
+		connections += dp.Count
+		if v, _ := dp.Attributes.Value(semconv.TLSProtocolVersionKey); v.AsString() != "1.3" {
+			t.Errorf("expected tls.protocol.version 1.3, got %q", v.AsString())
+		}
+		if v, _ := dp.Attributes.Value(semconv.NetworkProtocolVersionKey); v.AsString() != "1.1" {
+			t.Errorf("expected network.protocol.version 1.1, got %q", v.AsString())
+		}
+	}
+	if connections < newConnections {
+		t.Errorf("expected at least %d connection durations, got %d", newConnections, connections)
+	}
+
+	handshakeErrors, ok := found[serverTLSHandshakeErrorsName].(metricdata.Sum[int64])
+	if !ok || len(handshakeErrors.DataPoints) != 1 || handshakeErrors.DataPoints[0].Value != 1 {
+		t.Errorf("expected a single handshake error, got: %v", found[serverTLSHandshakeErrorsName])
+	}
+}
+
+func TestConnTrackerStates(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	tracker := &connTracker{
+		metrics:    newConnectionMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		conns:      make(map[net.Conn]*trackedConnState),
+		handshakes: make(map[net.Conn]struct{}),
+	}
+	server, client := net.Pipe()
+	defer client.Close()
+	tracker.track(server, http.StateNew)
+	tracker.track(server, http.StateActive)
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics failed due to: %v", err)
+	}
+	counts := map[attribute.Value]int64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == serverOpenConnectionsName {
+			for _, dp := range sum.DataPoints {
+				v, _ := dp.Attributes.Value(semconv.HTTPConnectionStateKey)
+				counts[v] = dp.Value
+			}
+		}
+	}
+	if counts[semconv.HTTPConnectionStateActive.Value] != 1 || counts[semconv.HTTPConnectionStateIdle.Value] != 0 {
+		t.Errorf("expected a single active connection, got %v", counts)
+	}
+}
+
+func TestProtocolVersionAttribute(t *testing.T) {
+	for _, mode := range []SemConvStability{SemConvStabilityOld, SemConvStabilityNew, SemConvStabilityDuplicate} {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")), WithSemConvStability(mode))(http.NotFoundHandler())
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		attributes := recorder.Ended()[0].Attributes()
+		if got := attributeValue(attributes, semconv.NetworkProtocolVersionKey); got.AsString() != "1.1" {
+			t.Errorf("expected network.protocol.version 1.1 in mode %d, got: %q", mode, got.AsString())
+		}
+		var count int
+		for _, kv := range attributes {
+			if kv.Key == semconv.NetworkProtocolVersionKey {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Errorf("expected a single network.protocol.version in mode %d, got: %d", mode, count)
+		}
+	}
+}
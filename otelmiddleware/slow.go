@@ -0,0 +1,66 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// slowRequestKey marks a request which exceeded the slow request threshold, it is also the name of the span event.
+	slowRequestKey = attribute.Key("slow_request")
+	// slowRequestThresholdKey records the threshold, in seconds, which the request exceeded.
+	slowRequestThresholdKey = attribute.Key("slow_request.threshold")
+	// slowRequestMessage is the message of the warning written for a slow request.
+	slowRequestMessage = "slow request"
+)
+
+// slowRequestThreshold returns the threshold of the route, when it has none the global threshold is returned.
+func (c *traceConfig) slowRequestThreshold(override *routeConfig) time.Duration {
+	if override != nil && override.slowThreshold > 0 {
+		return override.slowThreshold
+	}
+	return c.slowThreshold
+}
+
+// recordSlowRequest marks a request which took longer than the threshold on the span and writes a warning using the
+// slow request logger, a threshold of 0 disables the detection.
+func (c *traceConfig) recordSlowRequest(ctx context.Context, span trace.Span, r *http.Request, w WrapResponseWriter, elapsed, threshold time.Duration) {
+	if threshold <= 0 || elapsed <= threshold {
+		return
+	}
+	span.SetAttributes(slowRequestKey.Bool(true))
+	span.AddEvent(string(slowRequestKey), trace.WithAttributes(
+		slowRequestThresholdKey.Float64(threshold.Seconds()),
+		attribute.Float64(serverRequestDurationName, elapsed.Seconds()),
+	))
+	if c.slowLogger == nil {
+		return
+	}
+	statusCode := w.Status()
+	if statusCode == 0 {
+		// the handler did not write a response, the http.Server answers with 200 OK.
+		statusCode = http.StatusOK
+	}
+	attributes := accessLogAttributes(r, statusCode, w.BytesWritten(), elapsed)
+	attributes = append(attributes, slowRequestThresholdKey.Float64(threshold.Seconds()))
+	c.slowLogger.LogAccess(ctx, slog.LevelWarn, slowRequestMessage, span, attributes)
+}
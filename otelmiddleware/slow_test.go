@@ -0,0 +1,91 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithSlowRequestThreshold(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		path  string
+		delay time.Duration
+		slow  bool
+	}{
+		{desc: "fast request", path: "/", slow: false},
+		{desc: "slow request", path: "/", delay: 20 * time.Millisecond, slow: true},
+		{desc: "route threshold not exceeded", path: "/reports", delay: 20 * time.Millisecond, slow: false},
+		{desc: "route threshold exceeded", path: "/search", delay: 5 * time.Millisecond, slow: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			logger := &testAccessLogger{}
+
+			handler := TraceWithOptions(
+				WithTracer(provider.Tracer("test-tracer")),
+				WithSlowRequestThreshold(10*time.Millisecond, logger),
+				WithRouteOverride("/reports", WithRouteSlowRequestThreshold(time.Minute)),
+				WithRouteOverride("/search", WithRouteSlowRequestThreshold(time.Nanosecond)),
+			)(testHandler(func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(tC.delay)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tC.path, nil))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			span := spans[0]
+			if got := attributeValue(span.Attributes(), slowRequestKey).AsBool(); got != tC.slow {
+				t.Errorf("expected slow_request %t, got %t", tC.slow, got)
+			}
+			var events int
+			for _, event := range span.Events() {
+				if event.Name == string(slowRequestKey) {
+					events++
+				}
+			}
+			if !tC.slow {
+				if events != 0 || len(logger.entries) != 0 {
+					t.Errorf("expected no slow request event or warning, got %d and %d", events, len(logger.entries))
+				}
+				return
+			}
+			if events != 1 || len(logger.entries) != 1 {
+				t.Fatalf("expected a slow request event and warning, got %d and %d", events, len(logger.entries))
+			}
+			entry := logger.entries[0]
+			if entry.level != slog.LevelWarn {
+				t.Errorf("expected a warning, got %v", entry.level)
+			}
+			if !entry.spanContext.Equal(span.SpanContext()) {
+				t.Error("expected the warning to carry the span context of the request")
+			}
+			if _, ok := entry.attributes.Value(slowRequestThresholdKey); !ok {
+				t.Error("expected the threshold in the warning")
+			}
+		})
+	}
+}
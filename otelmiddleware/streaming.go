@@ -0,0 +1,61 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// timeToFirstByteKey records the seconds between the start of the request and sending the response header.
+	timeToFirstByteKey = attribute.Key("http.server.time_to_first_byte")
+	// flushCountKey records the number of times a streamed response has been flushed.
+	flushCountKey = attribute.Key("http.response.flush_count")
+	// firstByteEvent and lastFlushEvent mark the start and the end of a streamed response.
+	firstByteEvent = "http.response.first_byte"
+	lastFlushEvent = "http.response.last_flush"
+)
+
+// recordStreaming adds the time to first byte to the span. A flushed response, such as server-sent events or a chunked stream,
+// also records the flush count and the events marking the first byte and the last flush.
+func recordStreaming(span trace.Span, w WrapResponseWriter) {
+	sw, ok := w.(streamingWriter)
+	if !ok {
+		return
+	}
+	ttfb := sw.timeToFirstByte()
+	if ttfb <= 0 {
+		return
+	}
+	span.SetAttributes(timeToFirstByteKey.Float64(ttfb.Seconds()))
+	flushes := sw.flushCount()
+	if flushes == 0 {
+		return
+	}
+	span.SetAttributes(flushCountKey.Int(flushes))
+	span.AddEvent(firstByteEvent, trace.WithTimestamp(sw.firstByteTime()))
+	span.AddEvent(lastFlushEvent, trace.WithTimestamp(sw.lastFlushTime()), trace.WithAttributes(flushCountKey.Int(flushes)))
+}
+
+// streamingWriter is implemented by the writers returned by NewWrapResponseWriter.
+type streamingWriter interface {
+	timeToFirstByte() time.Duration
+	firstByteTime() time.Time
+	flushCount() int
+	lastFlushTime() time.Time
+}
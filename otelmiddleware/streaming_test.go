@@ -0,0 +1,128 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStreamingResponse(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+
+	handler := TraceWithOptions(
+		WithTracer(provider.Tracer("test-tracer")),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)(testHandler(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{"data: first\n\n", "data: second\n\n", "data: third\n\n"} {
+			_, _ = w.Write([]byte(event))
+			w.(http.Flusher).Flush()
+			time.Sleep(time.Millisecond)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 ended span, got: %d", len(spans))
+	}
+	span := spans[0]
+	if got := attributeValue(span.Attributes(), flushCountKey); got.AsInt64() != 3 {
+		t.Errorf("expected a flush count of 3, got: %d", got.AsInt64())
+	}
+	ttfb := attributeValue(span.Attributes(), timeToFirstByteKey).AsFloat64()
+	if ttfb <= 0 {
+		t.Errorf("expected a positive time to first byte, got: %f", ttfb)
+	}
+
+	events := map[string]time.Time{}
+	for _, event := range span.Events() {
+		events[event.Name] = event.Time
+	}
+	firstByte, ok := events[firstByteEvent]
+	if !ok {
+		t.Fatalf("expected a %s event", firstByteEvent)
+	}
+	lastFlush, ok := events[lastFlushEvent]
+	if !ok {
+		t.Fatalf("expected a %s event", lastFlushEvent)
+	}
+	if !lastFlush.After(firstByte) {
+		t.Errorf("expected the last flush %v to be after the first byte %v", lastFlush, firstByte)
+	}
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collecting metrics failed due to: %v", err)
+	}
+	var histogram metricdata.Histogram[float64]
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name == serverTimeToFirstByteName {
+			histogram, _ = m.Data.(metricdata.Histogram[float64])
+		}
+	}
+	if len(histogram.DataPoints) != 1 || histogram.DataPoints[0].Count != 1 {
+		t.Errorf("expected a single %s data point, got: %v", serverTimeToFirstByteName, histogram.DataPoints)
+	}
+}
+
+func TestFlushWithoutHeader(t *testing.T) {
+	w := NewWrapResponseWriter(httptest.NewRecorder(), 1)
+	w.(http.Flusher).Flush()
+
+	if w.Status() != http.StatusOK {
+		t.Errorf("expected a flush to send a 200 OK header, got: %d", w.Status())
+	}
+	sw := w.(streamingWriter)
+	if sw.flushCount() != 1 || sw.lastFlushTime().IsZero() {
+		t.Errorf("expected the flush to be recorded, got %d flushes at %v", sw.flushCount(), sw.lastFlushTime())
+	}
+	if sw.timeToFirstByte() <= 0 {
+		t.Errorf("expected a positive time to first byte, got: %v", sw.timeToFirstByte())
+	}
+}
+
+func TestNoStreamingAttributesWithoutFlush(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")))(testHandler(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	span := recorder.Ended()[0]
+	if got := attributeValue(span.Attributes(), flushCountKey); got.Type() != attribute.INVALID {
+		t.Errorf("expected no flush count on a response which is not flushed, got: %v", got.Emit())
+	}
+	if got := attributeValue(span.Attributes(), timeToFirstByteKey); got.Type() == attribute.INVALID {
+		t.Error("expected the time to first byte to be recorded")
+	}
+	if len(span.Events()) != 0 {
+		t.Errorf("expected no events, got: %d", len(span.Events()))
+	}
+}
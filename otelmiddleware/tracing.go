@@ -16,17 +16,25 @@ package otelmiddleware
 
 import (
 	"go.opentelemetry.io/otel/codes"
+	"net"
 	"net/http"
+	"net/netip"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
-	semconv "go.opentelemetry.io/otel/semconv/v1.11.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// version is used as the instrumentation version.
-const version = "0.1.0"
+const (
+	// instrumentationName is used as the name of the trace.Tracer and metric.Meter.
+	instrumentationName = "github.com/vincentfree/opentelemetry/otelmiddleware"
+	// version is used as the instrumentation version.
+	version = "0.1.0"
+)
 
 // TraceOption takes a traceConfig struct and applies changes.
 // It can be passed to the TraceWithOptions function to configure a traceConfig struct.
@@ -34,14 +42,57 @@ type TraceOption func(*traceConfig)
 
 // traceConfig contains all the configuration for the library.
 type traceConfig struct {
-	serviceName string
-	tracer      trace.Tracer
-	propagator  propagation.TextMapPropagator
-	attributes  []attribute.KeyValue
+	serviceName   string
+	tracer        trace.Tracer
+	propagator    propagation.TextMapPropagator
+	attributes    []attribute.KeyValue
+	meterProvider metric.MeterProvider
+	// spanNameFormatter returns the name of the server span.
+	spanNameFormatter func(*http.Request) string
+	// semconv selects the emitted version of the HTTP semantic conventions.
+	semconv SemConvStability
+	// filters exclude requests from tracing.
+	filters []Filter
+	// recordPanics enables recording of panics, the panic is re-raised when panicResponse is nil.
+	recordPanics  bool
+	panicResponse http.Handler
+	// requestHeaders and responseHeaders are recorded as span attributes, the values of redactedHeaders are replaced.
+	requestHeaders  []string
+	responseHeaders []string
+	redactedHeaders []string
+	// requestCapture, responseCapture and redacted are derived from the header configuration.
+	requestCapture  headerCapture
+	responseCapture headerCapture
+	redacted        map[string]struct{}
+	// bodyCapture enables capturing of request and response bodies when present.
+	bodyCapture *bodyCaptureConfig
+	// publicEndpointFn reports whether a request is served as a public endpoint.
+	publicEndpointFn  func(*http.Request) bool
+	dropPublicBaggage bool
+	// correlation configures the trace correlation headers written on the response.
+	correlation correlationHeaders
+	// loggerFactory places a request-scoped logger in the request context when present.
+	loggerFactory LoggerFactory
+	// connectionSpan keeps a child span open until a hijacked connection is closed.
+	connectionSpan bool
+	// statusClassifier decides whether a response is an error.
+	statusClassifier StatusClassifier
+	// trustedProxies are the networks whose forwarding headers are honored when resolving the client address.
+	trustedProxies []netip.Prefix
+	// requestID enables accepting or generating a request ID when present.
+	requestID *requestIDConfig
+	// routes holds the per-route overrides.
+	routes *routeOverrides
+	// slowThreshold marks requests taking longer as slow, the slowLogger writes a warning for them when present.
+	slowThreshold time.Duration
+	slowLogger    AccessLogger
+	// identityExtractor records the end user of a request when present, identityBaggage also adds it to the baggage.
+	identityExtractor IdentityExtractor
+	identityBaggage   bool
 }
 
-// TraceWithOptions takes TraceOption's and initializes a new trace.Span.
-func TraceWithOptions(opt ...TraceOption) func(next http.Handler) http.Handler {
+// newTraceConfig applies the TraceOption's to an empty traceConfig and sets default values for absent configuration.
+func newTraceConfig(opt []TraceOption) *traceConfig {
 	// initialize an empty traceConfig.
 	config := &traceConfig{}
 
@@ -51,7 +102,7 @@ func TraceWithOptions(opt ...TraceOption) func(next http.Handler) http.Handler {
 	}
 	// check for the traceConfig.tracer if absent use a default value.
 	if config.tracer == nil {
-		config.tracer = otel.Tracer("github.com/vincentfree/opentelemetry/otelmiddleware", trace.WithInstrumentationVersion(version))
+		config.tracer = otel.Tracer(instrumentationName, trace.WithInstrumentationVersion(version))
 	}
 	// check for the traceConfig.propagator if absent use a default value.
 	if config.propagator == nil {
@@ -61,43 +112,115 @@ func TraceWithOptions(opt ...TraceOption) func(next http.Handler) http.Handler {
 	if config.serviceName == "" {
 		config.serviceName = "TracedApplication"
 	}
+	// check for the traceConfig.meterProvider if absent use a default value.
+	if config.meterProvider == nil {
+		config.meterProvider = otel.GetMeterProvider()
+	}
+	// check for the traceConfig.statusClassifier if absent use a default value.
+	if config.statusClassifier == nil {
+		config.statusClassifier = DefaultStatusClassifier
+	}
+	// check for the traceConfig.spanNameFormatter if absent use a default value.
+	if config.spanNameFormatter == nil {
+		config.spanNameFormatter = defaultSpanNameFormatter
+	}
+	// derive the header capture configuration, the default redacted headers are always part of the set.
+	config.requestCapture = newHeaderCapture(config.requestHeaders)
+	config.responseCapture = newHeaderCapture(config.responseHeaders)
+	config.redacted = redactedHeaderSet(config.redactedHeaders)
+	// check for the traceConfig.semconv if absent resolve it from the environment.
+	if config.semconv == 0 {
+		config.semconv = semConvStabilityFromEnv()
+	}
+	return config
+}
+
+// TraceWithOptions takes TraceOption's and initializes a new trace.Span.
+func TraceWithOptions(opt ...TraceOption) func(next http.Handler) http.Handler {
+	config := newTraceConfig(opt)
+	// create the instruments once, they are shared by all requests passing through the handler.
+	metrics := newServerMetrics(config.meterProvider)
 	// the handler that initializes the trace.Span.
 	return func(next http.Handler) http.Handler {
 
 		// assign the handler which creates the OpenTelemetry trace.Span.
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
 			requestCtx := r.Context()
 			// extract the OpenTelemetry span context from the context.Context object.
 			ctx := config.propagator.Extract(requestCtx, propagation.HeaderCarrier(r.Header))
+			// public endpoints don't trust the incoming span context, it is linked to a new root span instead.
+			var publicOpts []trace.SpanStartOption
+			if config.public(r) {
+				ctx, publicOpts = config.untrust(ctx, r)
+			}
+			// filtered requests are served without a span or metrics, the extracted context keeps the incoming parent.
+			// a route with tracing disabled is served like a filtered request.
+			override := config.routes.match(r)
+			if filtered(config.filters, r) || (override != nil && override.disabled) {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+			// add a routeState to the context so the route can be tagged further down the chain.
+			ctx = withRouteState(ctx)
+			// the StatusClassifier is shared with the AccessLog middleware further down the chain.
+			ctx = withStatusClassifier(ctx, config.statusClassifier)
+			// resolve the client address once, behind trusted proxies it is taken from the forwarding headers.
+			client := config.clientAddress(r)
+			ctx = withClientAddress(ctx, client)
+			r = r.WithContext(ctx)
+			// the route is known up front when the middleware is applied to a handler registered on a http.ServeMux.
+			route := RouteFromRequest(r)
 			// the standard trace.SpanStartOption options whom are applied to every server handler.
 			opts := []trace.SpanStartOption{
-
-				trace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", r)...),
-				trace.WithAttributes(semconv.EndUserAttributesFromHTTPRequest(r)...),
-				trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(r.Host, extractRoute(r.RequestURI), r)...),
-				trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(r)...),
+				trace.WithAttributes(config.semconv.serverRequestAttributes(r, route, client)...),
 				trace.WithAttributes(semconv.TelemetrySDKLanguageGo),
 				trace.WithSpanKind(trace.SpanKindServer),
 			}
+			opts = append(opts, publicOpts...)
+			// the protocol version, the TLS state and, on a server instrumented using InstrumentServer, the reuse of the connection.
+			opts = append(opts, trace.WithAttributes(connectionAttributes(r)...))
+			// the attributes and sampling priority of the route are known to the sampler.
+			if override != nil {
+				opts = append(opts, override.startOptions()...)
+			}
 			// check for the traceConfig.attributes if present apply them to the trace.Span.
 			if len(config.attributes) > 0 {
 				opts = append(opts, trace.WithAttributes(config.attributes...))
 			}
-			// extract the route name which is used for setting a usable name of the span.
-			spanName := extractRoute(r.RequestURI)
-			if spanName == "" {
-				// no path available
-				spanName = r.Proto + " " + r.Method + " /"
+			// add the allow-listed request headers.
+			if config.requestCapture.enabled() {
+				opts = append(opts, trace.WithAttributes(config.requestCapture.attributes(requestHeaderPrefix, r.Header, config.redacted)...))
+			}
+			// record the end user, the enduser attributes take precedence over the ones of the semantic conventions.
+			if config.identityExtractor != nil {
+				if identity, ok := config.identityExtractor(r); ok {
+					opts = append(opts, trace.WithAttributes(identity.attributes()...))
+					if config.identityBaggage {
+						ctx = identity.withBaggage(ctx)
+					}
+				}
 			}
 
-			// create a good name to recognize where the span originated.
-			spanName = r.Method + " /" + spanName
-
-			// start the actual trace.Span.
-			ctx, span := config.tracer.Start(ctx, spanName, opts...)
+			// start the actual trace.Span, named by the configured span name formatter.
+			ctx, span := config.tracer.Start(ctx, config.spanNameFormatter(r), opts...)
 
 			defer span.End()
 
+			// the method attributes are shared between the span and the recorded metrics.
+			attributes := config.semconv.metricRequestAttributes(r)
+			defer metrics.requestStarted(ctx, attributes)()
+
+			// the request ID is assigned before the logger is created, the logging adapters read it from the baggage.
+			if config.requestID != nil {
+				ctx = config.requestID.assign(ctx, span, w, r)
+			}
+
+			// place a request-scoped logger carrying the trace context in the request context.
+			if config.loggerFactory != nil {
+				ctx = config.loggerFactory(ctx, span, loggerAttributes(r, route))
+			}
+
 			// pass the span through the request context.
 			r = r.WithContext(ctx)
 			carrier := propagation.HeaderCarrier(r.Header)
@@ -106,15 +229,98 @@ func TraceWithOptions(opt ...TraceOption) func(next http.Handler) http.Handler {
 			// use a wrapper for the http.responseWriter to capture the response status code;
 			// this information is added to the spans generated by the middleware
 			wrapperRes := NewWrapResponseWriter(w, r.ProtoMajor)
+			expectUpgrade(wrapperRes, r)
+			// the correlation headers are written right before the header, once it is written they would be lost.
+			if config.correlation.enabled() {
+				addHeaderHook(wrapperRes, func(header http.Header) {
+					config.correlation.write(header, span.SpanContext())
+				})
+			}
+
+			// a hijacked connection is reported as upgraded, the span ends once the handler returns.
+			addHijackHook(wrapperRes, func(conn net.Conn) net.Conn {
+				upgraded(span, r)
+				return conn
+			})
+			// the connection itself is only wrapped when it is tracked by a child span.
+			if config.connectionSpan {
+				addHijackHook(wrapperRes, func(conn net.Conn) net.Conn {
+					return config.trackConnection(ctx, r, conn)
+				})
+			}
 
-			// serve the request to the next middleware.
-			next.ServeHTTP(wrapperRes, r)
+			// count the bytes read from the request body and the time spent reading them.
+			requestBody := newCountingBody(r, span)
+
+			// capture the bodies of sampled requests, the request body is replaced so it can still be read by the handler.
+			var responseBody *limitedBuffer
+			if config.bodyCapture != nil && span.IsRecording() && config.bodyCapture.sampled() {
+				body, truncated := config.bodyCapture.captureRequest(r)
+				config.bodyCapture.record(span, requestBodyEvent, r.Header.Get("Content-Type"), body, truncated)
+				responseBody = &limitedBuffer{max: config.bodyCapture.maxBytes}
+				wrapperRes.Tee(responseBody)
+			}
+
+			// serve the request to the next middleware, watching for a client which goes away or a deadline which fires.
+			watcher := watchCancellation(ctx)
+			recovered := config.serve(next, wrapperRes, r, span)
+			// a handler which wrote nothing gets an implicit 200 OK, which still carries the headers of the hooks.
+			implicitHeader(wrapperRes)
+			cancellation := watcher.finish(ctx)
+			// a http.MaxBytesReader applied by the handler wraps the counted body, its error is not seen while reading.
+			requestBody.checkLimit(r)
+
+			// a http.ServeMux or TagRoute further down the chain might have matched the route while serving the request.
+			if matched := RouteFromRequest(r); matched != route {
+				route = matched
+				span.SetName(config.spanNameFormatter(r))
+				span.SetAttributes(config.semconv.routeAttributes(route)...)
+			}
+
+			statusCode := wrapperRes.Status()
+			code, description, errorType := config.statusClassifier(statusCode, r)
+			// the status code of a canceled request says little, it is classified by the reason of the cancellation.
+			if cancellation != "" {
+				code, description, errorType = cancellationStatus(cancellation)
+			}
+
+			// record the duration, status code and body sizes of the request.
+			attributes = append(attributes, config.semconv.routeAttributes(route)...)
+			attributes = append(attributes, config.semconv.metricResponseAttributes(statusCode)...)
+			if code == codes.Error {
+				metrics.requestFailed(ctx, attributes, errorType)
+			}
+			if cancellation != "" {
+				metrics.requestCanceled(ctx, attributes, cancellation)
+			}
+			attributes = append(attributes, config.semconv.errorTypeAttributes(errorType)...)
+			elapsed := time.Since(start)
+			metrics.requestFinished(ctx, requestBodySize(r, requestBody), wrapperRes, elapsed, attributes)
+			// find tail latency, the threshold of the route takes precedence over the global threshold.
+			config.recordSlowRequest(ctx, span, r, wrapperRes, elapsed, config.slowRequestThreshold(override))
 			// add the response status code to the span
 			if span.IsRecording() {
-				statusCode := wrapperRes.Status()
-				span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(statusCode)...)
-				if statusCode >= 500 {
-					span.SetStatus(codes.Error, http.StatusText(statusCode))
+				span.SetAttributes(config.semconv.responseAttributes(statusCode)...)
+				requestBody.record(span)
+				if cancellation != "" {
+					watcher.record(ctx, span, cancellation)
+				}
+				// streamed responses record the time to first byte and their flushes.
+				recordStreaming(span, wrapperRes)
+				// the content type of the response is only known after the handler wrote it.
+				if contentType := wrapperRes.Header().Get("Content-Type"); responseBody != nil && config.bodyCapture.allowed(contentType) {
+					config.bodyCapture.record(span, responseBodyEvent, contentType, responseBody.buf.Bytes(), responseBody.truncated)
+				}
+				// add the allow-listed response headers.
+				if config.responseCapture.enabled() {
+					span.SetAttributes(config.responseCapture.attributes(responseHeaderPrefix, wrapperRes.Header(), config.redacted)...)
+				}
+				if errorType != "" {
+					span.SetAttributes(config.semconv.errorTypeAttributes(errorType)...)
+				}
+				// a recovered panic already set the span status including the panic message.
+				if code != codes.Unset && !recovered {
+					span.SetStatus(code, description)
 				}
 			}
 		}
@@ -128,11 +334,6 @@ func Trace(next http.Handler) http.Handler {
 	return TraceWithOptions()(next)
 }
 
-// extract the route name.
-func extractRoute(uri string) string {
-	return uri[1:]
-}
-
 // WithTracer is a TraceOption to inject your own trace.Tracer.
 func WithTracer(tracer trace.Tracer) TraceOption {
 	return func(c *traceConfig) {
@@ -154,8 +355,240 @@ func WithServiceName(serviceName string) TraceOption {
 	}
 }
 
+// WithMeterProvider is a TraceOption to inject your own metric.MeterProvider.
+// The metric.MeterProvider is used to record the HTTP server metrics, when absent otel.GetMeterProvider is used.
+func WithMeterProvider(provider metric.MeterProvider) TraceOption {
+	return func(c *traceConfig) {
+		c.meterProvider = provider
+	}
+}
+
+// WithSpanNameFormatter is a TraceOption to set your own span name formatter.
+// By default the span is named after the method and route template, for example "GET /users/{id}",
+// or only the method when the route is unknown. The formatter is called when the span starts
+// and again after the request is served when a route got matched further down the chain.
+func WithSpanNameFormatter(formatter func(*http.Request) string) TraceOption {
+	return func(c *traceConfig) {
+		c.spanNameFormatter = formatter
+	}
+}
+
+// WithSemConvStability is a TraceOption to select the emitted version of the HTTP semantic conventions.
+// When absent the version is resolved from the OTEL_SEMCONV_STABILITY_OPT_IN environment variable,
+// "http" selects SemConvStabilityNew, "http/dup" selects SemConvStabilityDuplicate and SemConvStabilityOld is used otherwise.
+func WithSemConvStability(stability SemConvStability) TraceOption {
+	return func(c *traceConfig) {
+		c.semconv = stability
+	}
+}
+
+// WithFilter is a TraceOption to exclude requests from tracing, for example health checks and probes.
+// A request is excluded when one of the filters returns true, it is served without a span and without metrics.
+// The incoming span context is still extracted so downstream code keeps the parent.
+// WithFilter can be passed multiple times, the filters are combined.
+func WithFilter(filters ...Filter) TraceOption {
+	return func(c *traceConfig) {
+		c.filters = append(c.filters, filters...)
+	}
+}
+
+// WithPanicRecording is a TraceOption that records a panic raised by the next http.Handler on the span.
+// The panic is recorded as an exception event including the stack trace, the span status is set to error
+// after which the panic is raised again. A panic with http.ErrAbortHandler is passed on without being recorded.
+func WithPanicRecording() TraceOption {
+	return func(c *traceConfig) {
+		c.recordPanics = true
+		c.panicResponse = nil
+	}
+}
+
+// WithPanicRecovery is a TraceOption that recovers a panic raised by the next http.Handler and records it on the span,
+// like WithPanicRecording. Instead of raising the panic again the response http.Handler is used to answer the request,
+// when response is nil a plain 500 Internal Server Error is written. The response is only written when the next
+// http.Handler did not write the header yet, otherwise the response is aborted by raising http.ErrAbortHandler so the
+// client does not mistake the truncated response for a success. A panic with http.ErrAbortHandler is passed on without being recorded.
+func WithPanicRecovery(response http.Handler) TraceOption {
+	return func(c *traceConfig) {
+		if response == nil {
+			response = defaultPanicResponse
+		}
+		c.recordPanics = true
+		c.panicResponse = response
+	}
+}
+
+// WithRequestHeaders is a TraceOption to record request headers as http.request.header.<name> span attributes.
+// The header names are case-insensitive, "*" records all headers. Authorization, Proxy-Authorization, Cookie,
+// Set-Cookie and the headers passed to WithRedactedHeaders are always recorded with a redacted value.
+func WithRequestHeaders(headers ...string) TraceOption {
+	return func(c *traceConfig) {
+		c.requestHeaders = append(c.requestHeaders, headers...)
+	}
+}
+
+// WithResponseHeaders is a TraceOption to record response headers as http.response.header.<name> span attributes.
+// The header names are case-insensitive, "*" records all headers. Authorization, Proxy-Authorization, Cookie,
+// Set-Cookie and the headers passed to WithRedactedHeaders are always recorded with a redacted value.
+func WithResponseHeaders(headers ...string) TraceOption {
+	return func(c *traceConfig) {
+		c.responseHeaders = append(c.responseHeaders, headers...)
+	}
+}
+
+// WithRedactedHeaders is a TraceOption to extend the list of headers of which the value is never recorded,
+// even when all headers are captured using "*".
+func WithRedactedHeaders(headers ...string) TraceOption {
+	return func(c *traceConfig) {
+		c.redactedHeaders = append(c.redactedHeaders, headers...)
+	}
+}
+
+// WithBodyCapture is a TraceOption to record request and response bodies as http.request.body and http.response.body span events.
+// This is meant for debugging payload issues, by default up to 4096 bytes of JSON and text bodies are captured for every request.
+// The BodyCaptureOption functions configure the maximum size, the content types, the sampling ratio and a redaction hook.
+// The request body is replayed, so the handler can still read it in full.
+func WithBodyCapture(opts ...BodyCaptureOption) TraceOption {
+	return func(c *traceConfig) {
+		c.bodyCapture = newBodyCaptureConfig(opts)
+	}
+}
+
+// WithPublicEndpoint is a TraceOption for internet-facing services which should not trust the span context sent by clients.
+// Every request starts a new root span, the extracted remote span context is recorded as a span link instead of a parent.
+func WithPublicEndpoint() TraceOption {
+	return WithPublicEndpointFn(func(*http.Request) bool { return true })
+}
+
+// WithPublicEndpointFn is a TraceOption like WithPublicEndpoint, the function decides per request whether it is served as a public endpoint.
+func WithPublicEndpointFn(fn func(*http.Request) bool) TraceOption {
+	return func(c *traceConfig) {
+		c.publicEndpointFn = fn
+	}
+}
+
+// WithPublicBaggageDropped is a TraceOption that removes the incoming baggage of requests served as a public endpoint.
+func WithPublicBaggageDropped() TraceOption {
+	return func(c *traceConfig) {
+		c.dropPublicBaggage = true
+	}
+}
+
+// WithTraceResponseHeader is a TraceOption that writes the W3C traceresponse header on every response,
+// for example "traceresponse: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func WithTraceResponseHeader() TraceOption {
+	return func(c *traceConfig) {
+		c.correlation.traceResponse = true
+	}
+}
+
+// WithTraceIDHeader is a TraceOption that writes the trace ID on every response using the given header, for example "X-Trace-Id".
+func WithTraceIDHeader(header string) TraceOption {
+	return func(c *traceConfig) {
+		c.correlation.traceIDHeader = header
+	}
+}
+
+// WithServerTimingHeader is a TraceOption that adds a Server-Timing entry to every response, for example
+// `Server-Timing: traceparent;desc="00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"`.
+// This exposes the trace context to the browser through the Performance API.
+func WithServerTimingHeader() TraceOption {
+	return func(c *traceConfig) {
+		c.correlation.serverTiming = true
+	}
+}
+
+// WithLoggerFactory is a TraceOption to place a request-scoped logger in the request context.
+// The logger carries the trace and span ID, the method and, when it is known before the request is served, the route.
+// Pass the ContextWithTracing method of an otelslog, otelzerolog or otellogrus logger and retrieve the logger
+// in the handler using the FromContext function of the same package.
+func WithLoggerFactory(factory LoggerFactory) TraceOption {
+	return func(c *traceConfig) {
+		c.loggerFactory = factory
+	}
+}
+
+// WithUpgradedConnectionSpan is a TraceOption to track hijacked connections, for example WebSocket connections, using a child span.
+// The span ends when the hijacked net.Conn is closed and records the bytes read and written on the connection.
+func WithUpgradedConnectionSpan() TraceOption {
+	return func(c *traceConfig) {
+		c.connectionSpan = true
+	}
+}
+
+// WithStatusClassifier is a TraceOption to decide which responses are errors, for example to treat a 429 as an error
+// or a 503 during a drain as expected. The StatusClassifier drives the span status, the error.type attribute,
+// the http.server.request.errors metric and the level of the AccessLog. By default DefaultStatusClassifier is used.
+func WithStatusClassifier(classifier StatusClassifier) TraceOption {
+	return func(c *traceConfig) {
+		c.statusClassifier = classifier
+	}
+}
+
+// WithTrustedProxies is a TraceOption to resolve the client address behind load balancers and reverse proxies.
+// The proxies are passed as CIDRs, such as "10.0.0.0/8", or single IP addresses. The Forwarded, X-Forwarded-For and
+// X-Real-IP headers are only honored when the immediate peer is a trusted proxy, the forwarded addresses are read from
+// right to left and the first address which is not a trusted proxy is used as client.address.
+// Use ClientAddressFromRequest to read the resolved address in a handler.
+func WithTrustedProxies(proxies ...string) TraceOption {
+	return func(c *traceConfig) {
+		c.trustedProxies = append(c.trustedProxies, parseTrustedProxies(proxies)...)
+	}
+}
+
+// WithRequestID is a TraceOption to accept the request ID sent by clients in the X-Request-ID header, or generate one.
+// The request ID is recorded as the http.request.id span attribute, added to the baggage as RequestIDBaggageKey,
+// stored in the request context and echoed on the response. The RequestIDOption functions WithRequestIDHeader and
+// WithRequestIDGenerator configure the header and the generated ID. Use RequestIDFromContext to read the request ID.
+func WithRequestID(opts ...RequestIDOption) TraceOption {
+	return func(c *traceConfig) {
+		c.requestID = newRequestIDConfig(opts)
+	}
+}
+
+// WithRouteOverride is a TraceOption to configure the tracing of a route, for example "GET /users/{id}" or "/admin/".
+// The pattern uses the syntax and matching rules of http.ServeMux, a request is matched to the most specific pattern.
+// A malformed pattern, or a pattern conflicting with another override such as "/a/{x}" and "/a/{y}", is skipped and
+// reported to the global otel error handler.
+// The RouteOption functions WithRouteAttributes, WithRouteSamplingPriority and WithRouteTracingDisabled configure the route.
+func WithRouteOverride(pattern string, opts ...RouteOption) TraceOption {
+	return func(c *traceConfig) {
+		if c.routes == nil {
+			c.routes = &routeOverrides{}
+		}
+		c.routes.add(pattern, opts)
+	}
+}
+
+// WithSlowRequestThreshold is a TraceOption to mark requests taking longer than the threshold as slow. The span of a slow
+// request records the slow_request attribute and event, the threshold of a route is set using WithRouteSlowRequestThreshold.
+// When a logger is passed, a warning including the trace ID is written through the AccessLogger for every slow request.
+func WithSlowRequestThreshold(threshold time.Duration, logger AccessLogger) TraceOption {
+	return func(c *traceConfig) {
+		c.slowThreshold = threshold
+		c.slowLogger = logger
+	}
+}
+
+// WithIdentityExtractor is a TraceOption to record the end user of a request as the enduser.id, enduser.role, enduser.scope
+// and enduser.tenant span attributes. The package provides UnverifiedJWTIdentity, which reads the claims of a bearer token
+// or session cookie, any other IdentityExtractor can be passed as well.
+func WithIdentityExtractor(extractor IdentityExtractor) TraceOption {
+	return func(c *traceConfig) {
+		c.identityExtractor = extractor
+	}
+}
+
+// WithIdentityBaggage is a TraceOption to also add the enduser attributes of the IdentityExtractor to the baggage,
+// so they are propagated to downstream services. Only enable it when the downstream services are trusted with the identity.
+func WithIdentityBaggage() TraceOption {
+	return func(c *traceConfig) {
+		c.identityBaggage = true
+	}
+}
+
 // WithAttributes is a TraceOption to inject your own attributes.
-// Attributes are applied to the trace.Span.
+// Attributes are applied to the trace.Span. Passing it more than once replaces the attributes,
+// only the attributes of the last call are applied.
 func WithAttributes(attributes ...attribute.KeyValue) TraceOption {
 	return func(c *traceConfig) {
 		c.attributes = attributes
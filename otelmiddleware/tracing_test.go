@@ -134,3 +134,13 @@ func TestTraceFunctions(t *testing.T) {
 	}
 
 }
+
+func TestWithAttributesReplaces(t *testing.T) {
+	c := newTraceConfig([]TraceOption{
+		WithAttributes(attribute.String("first", "value")),
+		WithAttributes(attribute.String("second", "value")),
+	})
+	if len(c.attributes) != 1 || c.attributes[0].Key != "second" {
+		t.Errorf("expected only the attributes of the last WithAttributes, got: %v", c.attributes)
+	}
+}
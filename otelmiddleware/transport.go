@@ -0,0 +1,133 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport is a http.RoundTripper that creates a client trace.Span for every outgoing request
+// and injects the span context into the request headers using the configured propagation.TextMapPropagator.
+type Transport struct {
+	base   http.RoundTripper
+	config *traceConfig
+}
+
+var _ http.RoundTripper = &Transport{}
+
+// NewTransport wraps the base http.RoundTripper with tracing, when base is nil http.DefaultTransport is used.
+// It accepts the same TraceOption's as TraceWithOptions.
+func NewTransport(base http.RoundTripper, opt ...TraceOption) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		base:   base,
+		config: newTraceConfig(opt),
+	}
+}
+
+// NewClient returns a http.Client which uses a Transport based on http.DefaultTransport.
+// It accepts the same TraceOption's as TraceWithOptions.
+func NewClient(opt ...TraceOption) *http.Client {
+	return &http.Client{Transport: NewTransport(http.DefaultTransport, opt...)}
+}
+
+// RoundTrip starts a client trace.Span, injects the span context into a copy of the request and executes it
+// using the base http.RoundTripper. The span ends when the response body is closed or fully read.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// a RoundTripper should not modify the request, the attributes and headers are taken from and injected into a copy.
+	r = r.Clone(r.Context())
+	opts := []trace.SpanStartOption{
+		trace.WithAttributes(t.config.semconv.clientRequestAttributes(r)...),
+		trace.WithAttributes(semconv.TelemetrySDKLanguageGo),
+		trace.WithSpanKind(trace.SpanKindClient),
+	}
+	// check for the traceConfig.attributes if present apply them to the trace.Span.
+	if len(t.config.attributes) > 0 {
+		opts = append(opts, trace.WithAttributes(t.config.attributes...))
+	}
+
+	// an unknown method is replaced by "HTTP", this keeps the number of span names bounded.
+	ctx, span := t.config.tracer.Start(r.Context(), spanMethod(r.Method), opts...)
+
+	r = r.WithContext(ctx)
+	t.config.propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	res, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetAttributes(t.config.semconv.errorTypeAttributes(fmt.Sprintf("%T", err))...)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return res, err
+	}
+
+	span.SetAttributes(t.config.semconv.responseAttributes(res.StatusCode)...)
+	// a client considers 4xx and 5xx responses as an error.
+	if res.StatusCode >= 400 {
+		span.SetAttributes(t.config.semconv.errorTypeAttributes(strconv.Itoa(res.StatusCode))...)
+		span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+	}
+
+	// upgraded connections return a writable body which must be passed through untouched.
+	if res.Body == nil || res.Body == http.NoBody || res.StatusCode == http.StatusSwitchingProtocols {
+		span.End()
+		return res, nil
+	}
+	res.Body = &spanBody{ReadCloser: res.Body, span: span}
+	return res, nil
+}
+
+// spanBody ends the trace.Span once the response body has been read until io.EOF or is closed.
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+	once sync.Once
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	switch err {
+	case nil:
+	case io.EOF:
+		b.end()
+	default:
+		b.span.RecordError(err)
+		b.span.SetStatus(codes.Error, err.Error())
+		b.end()
+	}
+	return n, err
+}
+
+func (b *spanBody) Close() error {
+	b.end()
+	return b.ReadCloser.Close()
+}
+
+func (b *spanBody) end() {
+	b.once.Do(func() {
+		b.span.End()
+	})
+}
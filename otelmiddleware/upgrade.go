@@ -0,0 +1,103 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// upgradedEvent is the name of the span event recorded when the handler hijacks the connection.
+	upgradedEvent = "http.connection.upgraded"
+	// upgradedConnectionSpanName is the name of the span tracking a hijacked connection until it is closed.
+	upgradedConnectionSpanName = "upgraded connection"
+	// connectionBytesReadKey and connectionBytesWrittenKey record the traffic on a hijacked connection.
+	connectionBytesReadKey    = attribute.Key("http.connection.bytes_read")
+	connectionBytesWrittenKey = attribute.Key("http.connection.bytes_written")
+)
+
+// isUpgradeRequest reports whether the request asks to upgrade the protocol of the connection,
+// it requires an Upgrade header and an upgrade token in the Connection header.
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeAttributes returns the network.protocol.name attribute of the protocol requested in the Upgrade header, for example "websocket".
+func upgradeAttributes(r *http.Request) []attribute.KeyValue {
+	if protocol := r.Header.Get("Upgrade"); protocol != "" {
+		return []attribute.KeyValue{semconv.NetworkProtocolName(strings.ToLower(protocol))}
+	}
+	return nil
+}
+
+// upgraded records the hijacked connection on the span.
+func upgraded(span trace.Span, r *http.Request) {
+	span.AddEvent(upgradedEvent, trace.WithAttributes(upgradeAttributes(r)...))
+}
+
+// trackConnection returns a net.Conn which tracks the bytes read and written and ends a child span once it is closed.
+func (c *traceConfig) trackConnection(ctx context.Context, r *http.Request, conn net.Conn) net.Conn {
+	_, connSpan := c.tracer.Start(ctx, upgradedConnectionSpanName, trace.WithAttributes(upgradeAttributes(r)...))
+	return &trackedConn{Conn: conn, span: connSpan}
+}
+
+// trackedConn counts the bytes read and written on a hijacked net.Conn, the span ends when the connection is closed.
+type trackedConn struct {
+	net.Conn
+	span    trace.Span
+	read    atomic.Int64
+	written atomic.Int64
+	once    sync.Once
+}
+
+func (c *trackedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *trackedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		c.span.SetAttributes(connectionBytesReadKey.Int64(c.read.Load()), connectionBytesWrittenKey.Int64(c.written.Load()))
+		c.span.End()
+	})
+	return err
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmiddleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const switchingProtocols = "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"
+
+// upgradeHandler hijacks the connection, answers a "ping" with a "pong" and closes the connection.
+func upgradeHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("failed to hijack the connection: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString(switchingProtocols)
+		_ = rw.Flush()
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(rw, buf); err != nil {
+			t.Errorf("failed to read from the connection: %v", err)
+			return
+		}
+		_, _ = conn.Write([]byte("pong"))
+	})
+}
+
+// upgrade sends an upgrade request followed by a "ping" and reads the response until the connection is closed.
+func upgrade(t *testing.T, addr string) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\nping"))
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("failed to read the response: %v", err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status 101, got: %d", res.StatusCode)
+	}
+	_, _ = io.ReadAll(res.Body)
+}
+
+func waitForSpans(recorder *tracetest.SpanRecorder, n int) []sdktrace.ReadOnlySpan {
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.Ended()) < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return recorder.Ended()
+}
+
+func TestUpgradedConnection(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")), WithSemConvStability(SemConvStabilityNew))(upgradeHandler(t))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	upgrade(t, server.Listener.Addr().String())
+
+	spans := waitForSpans(recorder, 1)
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 ended span, got: %d", len(spans))
+	}
+	span := spans[0]
+	if got := attributeValue(span.Attributes(), "http.response.status_code"); got.AsInt64() != http.StatusSwitchingProtocols {
+		t.Errorf("expected status code 101, got: %d", got.AsInt64())
+	}
+	var found bool
+	for _, event := range span.Events() {
+		if event.Name == upgradedEvent {
+			found = true
+			if got := attributeValue(event.Attributes, "network.protocol.name"); got.AsString() != "websocket" {
+				t.Errorf("expected protocol websocket, got: %q", got.AsString())
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected a %s event", upgradedEvent)
+	}
+}
+
+func TestWithUpgradedConnectionSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")), WithUpgradedConnectionSpan())(upgradeHandler(t))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	upgrade(t, server.Listener.Addr().String())
+
+	spans := waitForSpans(recorder, 2)
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 ended spans, got: %d", len(spans))
+	}
+	// the connection span ends when the handler closes the connection, before the server span.
+	connSpan, serverSpan := spans[0], spans[1]
+	if connSpan.Name() != upgradedConnectionSpanName {
+		t.Fatalf("expected the %q span to end first, got: %q", upgradedConnectionSpanName, connSpan.Name())
+	}
+	if connSpan.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Error("expected the connection span to be a child of the server span")
+	}
+	// the "ping" might be buffered while reading the request, those bytes are not read from the net.Conn again.
+	if got := attributeValue(connSpan.Attributes(), connectionBytesWrittenKey); got.AsInt64() != int64(len(switchingProtocols)+len("pong")) {
+		t.Errorf("expected %d bytes written, got: %d", len(switchingProtocols)+len("pong"), got.AsInt64())
+	}
+	if got := attributeValue(connSpan.Attributes(), connectionBytesReadKey); got.Type() != attribute.INT64 {
+		t.Errorf("expected attribute %s to be present", connectionBytesReadKey)
+	}
+}
+
+func attributeValue(attributes []attribute.KeyValue, key attribute.Key) attribute.Value {
+	set := attribute.NewSet(attributes...)
+	value, _ := set.Value(key)
+	return value
+}
+
+func TestHijackedWithoutUpgrade(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	// the handler takes over the connection of a request which did not ask for an upgrade.
+	handler := TraceWithOptions(WithTracer(provider.Tracer("test-tracer")), WithSemConvStability(SemConvStabilityNew))(testHandler(func(w http.ResponseWriter, _ *http.Request) {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("failed to hijack the connection: %v", err)
+			return
+		}
+		_, _ = conn.Write([]byte("raw"))
+		_ = conn.Close()
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	_, _ = io.ReadAll(conn)
+
+	spans := waitForSpans(recorder, 1)
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 ended span, got: %d", len(spans))
+	}
+	if got := attributeValue(spans[0].Attributes(), "http.response.status_code"); got.Type() != attribute.INVALID {
+		t.Errorf("expected no status code, got: %d", got.AsInt64())
+	}
+}
+
+// hijackRecorder is a http.ResponseWriter which hands out its conn and rw when hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, h.rw, nil
+}
+
+func TestHijackKeepsReadWriter(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		opts    []TraceOption
+		wrapped bool
+	}{
+		{desc: "without connection span"},
+		{desc: "with connection span", opts: []TraceOption{WithUpgradedConnectionSpan()}, wrapped: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			conn, peer := net.Pipe()
+			defer conn.Close()
+			defer peer.Close()
+			w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder(), conn: conn, rw: bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))}
+
+			var hijackedConn net.Conn
+			var hijackedRW *bufio.ReadWriter
+			handler := TraceWithOptions(tC.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hijackedConn, hijackedRW, _ = http.NewResponseController(w).Hijack()
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			r.Header.Set("Upgrade", "websocket")
+			r.Header.Set("Connection", "Upgrade")
+			handler.ServeHTTP(w, r)
+
+			if wrapped := hijackedConn != conn; wrapped != tC.wrapped {
+				t.Errorf("expected the connection to be wrapped: %t, got: %t", tC.wrapped, wrapped)
+			}
+			if rewrapped := hijackedRW != w.rw; rewrapped != tC.wrapped {
+				t.Errorf("expected the bufio.ReadWriter to be replaced: %t, got: %t", tC.wrapped, rewrapped)
+			}
+		})
+	}
+}
@@ -41,6 +41,7 @@ Functions
 	func SetLogOptions(options ...LogOption)
 	func WithTraceID(traceID string) LogOption
 	func WithSpanID(spanID string) LogOption
+	func WithRequestID(requestID string) LogOption
 	func WithServiceName(serviceName string) LogOption
 	func WithAttributePrefix(prefix string) LogOption
 	func WithAttributes(attributes ...attribute.KeyValue) LogOption
@@ -50,6 +51,10 @@ Functions
 	func NewWithHandler(handler slog.Handler) *Logger
 	func (l Logger) WithTracingContext(ctx context.Context, level slog.Level, msg string, span trace.Span, err error, attrs ...slog.Attr)
 	func (l Logger) WithTracingContextAndAttributes(ctx context.Context, level slog.Level, msg string, span trace.Span, err error, attributes []attribute.KeyValue, attrs ...slog.Attr)
+	func (l *Logger) ContextWithTracing(ctx context.Context, span trace.Span, attributes []attribute.KeyValue) context.Context
+	func (l *Logger) LogAccess(ctx context.Context, level slog.Level, msg string, span trace.Span, attributes []attribute.KeyValue)
+	func NewContext(ctx context.Context, l *Logger) context.Context
+	func FromContext(ctx context.Context) *Logger
 
 Types
 
@@ -198,3 +198,13 @@ func ExampleLogger_ConvertToSlogFormat() {
 	logger.LogAttrs(nil, slog.LevelInfo, "test", attrs...)
 	// Output: {"level":"INFO","msg":"test","init":"attr","trace.attribute.stringExample":"this is an example string","trace.attribute.float64Example":42,"trace.attribute.int64Example":42,"trace.attribute.boolExample":true,"trace.attribute.boolSliceExample.0":true,"trace.attribute.boolSliceExample.1":false,"trace.attribute.boolSliceExample.2":true,"trace.attribute.int64SliceExample.0":42,"trace.attribute.int64SliceExample.1":9223372036854775807,"trace.attribute.float64SliceExample.0":42,"trace.attribute.float64SliceExample.1":3.141592653589793,"trace.attribute.stringSliceExample.0":"test","trace.attribute.stringSliceExample.1":"values"}
 }
+
+func ExampleLogger_ContextWithTracing() {
+	tracer := otel.Tracer("otelslog/example")
+	ctx, span := tracer.Start(context.Background(), "example-span")
+	logger := otelslog.New(otelslog.WithProvidedHandler(slog.NewJSONHandler(os.Stdout, timeRemoved)))
+	// store a logger carrying the trace context, this method can be passed to otelmiddleware.WithLoggerFactory.
+	ctx = logger.ContextWithTracing(ctx, span, nil)
+	otelslog.FromContext(ctx).Info("handling request")
+	// Output: {"level":"INFO","msg":"handling request","traceID":"00000000000000000000000000000000","spanID":"0000000000000000"}
+}
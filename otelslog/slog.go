@@ -36,6 +36,7 @@ type logConfig struct {
 	serviceName      string
 	traceId          string
 	spanId           string
+	requestId        string
 	attributePrefix  string
 	handler          *otelslogger.Handler
 	overwriteHandler slog.Handler
@@ -49,6 +50,8 @@ type Logger struct {
 	defaultTraceId string
 	// defaultSpanId has a default span ID key in the logs
 	defaultSpanId string
+	// defaultRequestId has a default request ID key in the logs
+	defaultRequestId string
 	// defaultServiceName is empty by default, when no value is set the service name won't be used with a default in the logs
 	defaultServiceName string
 	// defaultAttributes contains a global set of attribute.KeyValue's that will be added to very structured log. when the slice is empty they won't be added
@@ -62,6 +65,7 @@ func defaultLogger() *Logger {
 		Logger:             slog.New(slog.NewJSONHandler(os.Stdout, nil)),
 		defaultTraceId:     "traceID",
 		defaultSpanId:      "spanID",
+		defaultRequestId:   "requestID",
 		defaultServiceName: "",
 		defaultAttributes:  []attribute.KeyValue(nil),
 		defaultAttrPrefix:  "trace.attribute",
@@ -108,6 +112,10 @@ func initLogger(options []LogOption) *Logger {
 		logger.defaultSpanId = config.spanId
 	}
 
+	if config.requestId != "" {
+		logger.defaultRequestId = config.requestId
+	}
+
 	if config.serviceName != "" {
 		logger.defaultServiceName = config.serviceName
 	}
@@ -153,6 +161,13 @@ func WithTraceID(traceID string) LogOption {
 	}
 }
 
+// WithRequestID overwrites the default 'requestID' field in the structured logs with your own key
+func WithRequestID(requestID string) LogOption {
+	return func(c *logConfig) {
+		c.requestId = requestID
+	}
+}
+
 // WithSpanID overwrites the default 'spanID' field in the structured logs with your own key
 func WithSpanID(spanID string) LogOption {
 	return func(c *logConfig) {
@@ -0,0 +1,104 @@
+// Copyright 2024 Vincent Free
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelzerolog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/rs/zerolog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDBaggageKey is the baggage member carrying the request ID, it matches otelmiddleware.RequestIDBaggageKey.
+const requestIDBaggageKey = "request.id"
+
+// loggerKey is the context.Context key under which a Logger is stored.
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx which carries the Logger, it can be retrieved using FromContext.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the Logger stored in ctx by NewContext or ContextWithTracing.
+// When ctx does not carry a Logger, the global Logger of the library is returned.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return l
+	}
+	return _logger
+}
+
+// ContextWithTracing returns a copy of ctx which carries a Logger derived from l.
+// The derived Logger adds the trace context of the span, the attributes and the request ID in the baggage of ctx to every log.
+// The method matches the otelmiddleware.LoggerFactory signature, so a request-scoped Logger can be placed in the request context.
+func (l Logger) ContextWithTracing(ctx context.Context, span trace.Span, attributes []attribute.KeyValue) context.Context {
+	zctx := l.With().
+		Str(l.defaultTraceId, span.SpanContext().TraceID().String()).
+		Str(l.defaultSpanId, span.SpanContext().SpanID().String())
+	// set service.name if the value isn't empty
+	if l.defaultServiceName != "" {
+		zctx = zctx.Str("service.name", l.defaultServiceName)
+	}
+	if id := requestID(ctx); id != "" {
+		zctx = zctx.Str(l.defaultRequestId, id)
+	}
+	attrs := append(append([]attribute.KeyValue(nil), attributes...), l.defaultAttributes...)
+	for _, attr := range attrs {
+		if attr.Value.Type() == attribute.INVALID {
+			continue
+		}
+		zctx = zctx.Interface(l.defaultAttrPrefix+"."+string(attr.Key), attr.Value.AsInterface())
+	}
+
+	child := l
+	child.Logger = zctx.Logger()
+	// the trace context and attributes are part of the derived logger, they should not be added twice.
+	child.defaultAttributes = nil
+	return NewContext(ctx, child)
+}
+
+// LogAccess writes an access log line with the trace context of the span and the attributes.
+// The method implements the otelmiddleware.AccessLogger interface, the slog.Level is mapped to the closest zerolog.Level.
+func (l Logger) LogAccess(ctx context.Context, level slog.Level, msg string, span trace.Span, attributes []attribute.KeyValue) {
+	event := l.WithLevel(zerologLevel(level)).Ctx(ctx).Func(l.AddTracingContextWithAttributes(span, attributes))
+	if id := requestID(ctx); id != "" {
+		event = event.Str(l.defaultRequestId, id)
+	}
+	event.Msg(msg)
+}
+
+// requestID returns the request ID carried in the baggage of ctx, as set by the otelmiddleware.WithRequestID option.
+func requestID(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(requestIDBaggageKey).Value()
+}
+
+// zerologLevel maps a slog.Level to a zerolog.Level.
+func zerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zerolog.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zerolog.WarnLevel
+	case level >= slog.LevelInfo:
+		return zerolog.InfoLevel
+	default:
+		return zerolog.DebugLevel
+	}
+}
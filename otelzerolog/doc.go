@@ -27,11 +27,16 @@ Functions
 	func SetLogOptions(options ...LogOption)
 	func WithTraceID(traceID string) LogOption
 	func WithSpanID(spanID string) LogOption
+	func WithRequestID(requestID string) LogOption
 	func WithServiceName(serviceName string) LogOption
 	func WithAttributePrefix(prefix string) LogOption
 	func WithAttributes(attributes ...attribute.KeyValue) LogOption
 	func AddTracingContext(span trace.Span, err ...error) func(event *zerolog.Event)
 	func AddTracingContextWithAttributes(span trace.Span, attributes []attribute.KeyValue, err ...error) func(event *zerolog.Event)
+	func (l Logger) ContextWithTracing(ctx context.Context, span trace.Span, attributes []attribute.KeyValue) context.Context
+	func (l Logger) LogAccess(ctx context.Context, level slog.Level, msg string, span trace.Span, attributes []attribute.KeyValue)
+	func NewContext(ctx context.Context, l Logger) context.Context
+	func FromContext(ctx context.Context) Logger
 
 Types
 
@@ -177,3 +177,14 @@ func ExampleSetGlobalLogger() {
 	// Output: {"level":"info","traceID":"00000000000000000000000000000000","spanID":"0000000000000000","trace.attribute.test":"value","trace.attribute.isValid":true,"message":"in case of a success"}
 	// {"level":"error","error":"example error","traceID":"00000000000000000000000000000000","spanID":"0000000000000000","trace.attribute.test":"value","trace.attribute.isValid":true,"message":"in case of a failure"}
 }
+
+func ExampleLogger_ContextWithTracing() {
+	tracer := otel.Tracer("otelzerolog/ExampleContextWithTracing")
+	ctx, span := tracer.Start(context.Background(), "example-span")
+	defer span.End()
+	logger := otelzerolog.New()
+	// store a logger carrying the trace context, this method can be passed to otelmiddleware.WithLoggerFactory.
+	ctx = logger.ContextWithTracing(ctx, span, nil)
+	l := otelzerolog.FromContext(ctx)
+	l.Info().Msg("handling request")
+}
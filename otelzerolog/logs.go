@@ -35,6 +35,7 @@ type logConfig struct {
 	serviceName     string
 	traceId         string
 	spanId          string
+	requestId       string
 	attributePrefix string
 	hook            *otelzlog.Hook
 	zeroLogFeatures []func(zerolog.Context) zerolog.Context
@@ -47,6 +48,8 @@ type Logger struct {
 	defaultTraceId string
 	// _spanId has a default span ID key in the logs
 	defaultSpanId string
+	// _requestId has a default request ID key in the logs
+	defaultRequestId string
 	// _serviceName is empty by default, when no value is set the service name won't be used with a default in the logs
 	defaultServiceName string
 	// _attributes contains a global set of attribute.KeyValue's that will be added to very structured log. when the slice is empty they won't be added
@@ -60,6 +63,7 @@ func defaultLogger() Logger {
 	logger.Logger = log.Logger
 	logger.defaultTraceId = "traceID"
 	logger.defaultSpanId = "spanID"
+	logger.defaultRequestId = "requestID"
 	logger.defaultAttrPrefix = "trace.attribute"
 	return logger
 }
@@ -118,6 +122,9 @@ func initLogger(options LogOptions) Logger {
 	if config.spanId != "" {
 		logger.defaultSpanId = config.spanId
 	}
+	if config.requestId != "" {
+		logger.defaultRequestId = config.requestId
+	}
 
 	if config.serviceName != "" {
 		logger.defaultServiceName = config.serviceName
@@ -172,6 +179,13 @@ func WithTraceID(traceID string) LogOption {
 	}
 }
 
+// WithRequestID overwrites the default 'requestID' field in the structured logs with your own key
+func WithRequestID(requestID string) LogOption {
+	return func(c *logConfig) {
+		c.requestId = requestID
+	}
+}
+
 // WithSpanID overwrites the default 'spanID' field in the structured logs with your own key
 func WithSpanID(spanID string) LogOption {
 	return func(c *logConfig) {
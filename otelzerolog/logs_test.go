@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
 	"testing"
@@ -26,6 +27,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"golang.org/x/exp/constraints"
 	"golang.org/x/exp/slices"
 )
@@ -260,3 +262,49 @@ func idCheck(t *testing.T, name string, value any, length int) {
 		t.Errorf("%s should be in the log", name)
 	}
 }
+
+func TestLogger_ContextWithTracing(t *testing.T) {
+	_, span := otel.Tracer("test").Start(context.Background(), "serviceName")
+	out := captureLog(t, New(), func(logger Logger) {
+		ctx := logger.ContextWithTracing(context.Background(), span, []attribute.KeyValue{attribute.String("http.route", "/users/{id}")})
+		l := FromContext(ctx)
+		l.Info().Msg("test")
+	})
+	data := logToMap(t, out)
+
+	idCheck(t, "traceID", data["traceID"], 32)
+	idCheck(t, "spanID", data["spanID"], 16)
+	attributeCheck(t, data["trace.attribute.http.route"], "/users/{id}")
+}
+
+func TestLogger_LogAccess(t *testing.T) {
+	_, span := otel.Tracer("test").Start(context.Background(), "serviceName")
+	out := captureLog(t, New(), func(logger Logger) {
+		logger.LogAccess(context.Background(), slog.LevelWarn, "request completed", span, []attribute.KeyValue{attribute.Int("http.response.status_code", 404)})
+	})
+	data := logToMap(t, out)
+
+	attributeCheck(t, data["level"], "warn")
+	attributeCheck(t, data["message"], "request completed")
+	idCheck(t, "traceID", data["traceID"], 32)
+	idCheck(t, "spanID", data["spanID"], 16)
+	attributeCheck(t, data["trace.attribute.http.response.status_code"], float64(404))
+}
+
+func TestLogger_RequestID(t *testing.T) {
+	_, span := otel.Tracer("test").Start(context.Background(), "serviceName")
+	member, _ := baggage.NewMemberRaw(requestIDBaggageKey, "0190b5a4-6b2c-7d1e-8f00-000000000001")
+	bag, _ := baggage.New(member)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	out := captureLog(t, New(), func(logger Logger) {
+		l := FromContext(logger.ContextWithTracing(ctx, span, nil))
+		l.Info().Msg("test")
+	})
+	attributeCheck(t, logToMap(t, out)["requestID"], "0190b5a4-6b2c-7d1e-8f00-000000000001")
+
+	out = captureLog(t, New(WithRequestID("request_id")), func(logger Logger) {
+		logger.LogAccess(ctx, slog.LevelInfo, "request completed", span, nil)
+	})
+	attributeCheck(t, logToMap(t, out)["request_id"], "0190b5a4-6b2c-7d1e-8f00-000000000001")
+}